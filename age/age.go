@@ -0,0 +1,120 @@
+// Package age encrypts and decrypts data with age X25519 recipients and
+// identity files.
+package age
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"filippo.io/age"
+)
+
+// IdentityFile is the path to the file holding the age identities used for
+// decryption.
+var IdentityFile string
+
+// Encrypt a byte to the given recipients. If no recipients are given, the
+// recipients for the identities in IdentityFile are used.
+func Encrypt(b []byte, recipients ...string) ([]byte, error) {
+	to, err := parseRecipients(recipients)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, to...)
+	if err != nil {
+		return nil, fmt.Errorf("age encrypt failed: %v", err)
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, fmt.Errorf("age encrypt failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("age encrypt failed: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decrypt a byte with the identities in IdentityFile.
+func Decrypt(b []byte) ([]byte, error) {
+	identities, err := readIdentities()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(b), identities...)
+	if err != nil {
+		return nil, fmt.Errorf("age decrypt failed: %v", err)
+	}
+
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("age decrypt failed: %v", err)
+	}
+	return out, nil
+}
+
+func parseRecipients(recipients []string) ([]age.Recipient, error) {
+	to := []age.Recipient{}
+	for _, recipient := range recipients {
+		if len(recipient) == 0 {
+			continue
+		}
+		r, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("parsing age recipient %q failed: %v", recipient, err)
+		}
+		to = append(to, r)
+	}
+	if len(to) > 0 {
+		return to, nil
+	}
+
+	// Encrypt to ourselves.
+	identities, err := readIdentities()
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range identities {
+		if x, ok := i.(*age.X25519Identity); ok {
+			to = append(to, x.Recipient())
+		}
+	}
+	if len(to) == 0 {
+		return nil, fmt.Errorf("no X25519 identities found in %s", IdentityFile)
+	}
+	return to, nil
+}
+
+func readIdentities() ([]age.Identity, error) {
+	f, err := os.Open(IdentityFile)
+	if err != nil {
+		return nil, fmt.Errorf("opening age identity file failed: %v (generate one with `age-keygen -o %s`)", err, IdentityFile)
+	}
+	defer f.Close()
+
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing age identity file %s failed: %v", IdentityFile, err)
+	}
+	return identities, nil
+}
+
+// Recipients returns the recipients for the identities in IdentityFile, which
+// is who Encrypt encrypts to when no recipients are given.
+func Recipients() ([]string, error) {
+	to, err := parseRecipients(nil)
+	if err != nil {
+		return nil, err
+	}
+	recipients := []string{}
+	for _, r := range to {
+		if x, ok := r.(*age.X25519Recipient); ok {
+			recipients = append(recipients, x.String())
+		}
+	}
+	return recipients, nil
+}
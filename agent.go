@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const agentHelp = `Run an agent that keeps the decrypted secrets in memory.`
+
+const agentLongHelp = agentHelp + `
+
+The agent decrypts the secrets file once and serves get, ls, create, and rm
+over a Unix socket that only your user can access. Set PONY_AGENT_SOCK to the
+socket path and those commands use the agent instead of decrypting the file.
+The agent only serves the --file it was started with and refuses requests for
+any other secrets file. The agent exits after it has not been used for
+--idle-timeout.
+
+Example:
+
+  pony agent &
+  export PONY_AGENT_SOCK=/tmp/pony-1000/agent.sock`
+
+func (cmd *agentCommand) Name() string      { return "agent" }
+func (cmd *agentCommand) Args() string      { return "[OPTIONS]" }
+func (cmd *agentCommand) ShortHelp() string { return agentHelp }
+func (cmd *agentCommand) LongHelp() string  { return agentLongHelp }
+func (cmd *agentCommand) Hidden() bool      { return false }
+
+func (cmd *agentCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.socket, "socket", os.Getenv("PONY_AGENT_SOCK"), "path of the socket to listen on, defaults to agent.sock in a private directory in the temp dir (or env var PONY_AGENT_SOCK)")
+	fs.DurationVar(&cmd.idleTimeout, "idle-timeout", 15*time.Minute, "exit after not serving any requests for this long, 0 to never exit")
+}
+
+type agentCommand struct {
+	socket      string
+	idleTimeout time.Duration
+
+	// file is the absolute path of the secrets file the agent serves.
+	file string
+
+	// mu guards the secrets and sum while a request is handled.
+	mu sync.Mutex
+	// sum is the checksum of the secrets file the secrets were read from,
+	// so changes made without the agent are noticed.
+	sum [sha256.Size]byte
+}
+
+// agentRequest is a request sent to the agent over the socket. It carries
+// the secrets file and the global flags of the client, so the agent writes
+// the file the same way the client would have.
+type agentRequest struct {
+	Op    string   `json:"op"`
+	Key   string   `json:"key,omitempty"`
+	Value string   `json:"value,omitempty"`
+	Force bool     `json:"force,omitempty"`
+	Note  string   `json:"note,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+
+	File          string   `json:"file"`
+	HistoryDepth  int      `json:"history_depth"`
+	Keyids        []string `json:"keyids,omitempty"`
+	KeyidsChanged bool     `json:"keyids_changed,omitempty"`
+	Backend       string   `json:"backend,omitempty"`
+}
+
+// agentResponse is the reply of the agent to a request.
+type agentResponse struct {
+	Error      string            `json:"error,omitempty"`
+	Secrets    map[string]secret `json:"secrets,omitempty"`
+	Recipients []string          `json:"recipients,omitempty"`
+}
+
+func (cmd *agentCommand) Run(ctx context.Context, args []string) error {
+	// Keep the decrypted secrets out of swap.
+	if err := lockMemory(); err != nil {
+		logrus.Warnf("locking memory failed, the decrypted secrets may be swapped to disk: %v", err)
+	}
+
+	var err error
+	cmd.file, err = absFile(file)
+	if err != nil {
+		return err
+	}
+	sum, err := fileSum(cmd.file)
+	if err != nil {
+		return err
+	}
+	cmd.sum = sum
+
+	// Other pony processes can use the secrets file while the agent runs.
+	if err := unlockFile(lock); err != nil {
+		return err
+	}
+	lock = nil
+
+	if len(cmd.socket) == 0 {
+		cmd.socket = filepath.Join(os.TempDir(), fmt.Sprintf("pony-%d", os.Getuid()), "agent.sock")
+	}
+	l, err := listenAgent(cmd.socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(cmd.socket)
+
+	// Stop on signals and when the agent has been idle for too long.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		l.Close()
+	}()
+
+	var idle *time.Timer
+	if cmd.idleTimeout > 0 {
+		idle = time.AfterFunc(cmd.idleTimeout, func() {
+			logrus.Infof("agent idle for %s, exiting", cmd.idleTimeout)
+			l.Close()
+		})
+	}
+
+	fmt.Printf("Agent listening on %s\n", cmd.socket)
+	fmt.Printf("export PONY_AGENT_SOCK=%s\n", cmd.socket)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			break
+		}
+		if idle != nil {
+			idle.Reset(cmd.idleTimeout)
+		}
+		go cmd.serve(conn, idle)
+	}
+
+	// Do not keep the secrets around any longer than needed.
+	cmd.mu.Lock()
+	s = secretFile{}
+	cmd.mu.Unlock()
+
+	return nil
+}
+
+// serve handles the requests of a single client connection.
+func (cmd *agentCommand) serve(conn net.Conn, idle *time.Timer) {
+	defer conn.Close()
+
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		var req agentRequest
+		if err := dec.Decode(&req); err != nil {
+			if err != io.EOF {
+				logrus.Debugf("reading agent request failed: %v", err)
+			}
+			return
+		}
+		if idle != nil {
+			idle.Reset(cmd.idleTimeout)
+		}
+
+		resp, err := cmd.handle(req)
+		if err != nil {
+			resp = agentResponse{Error: err.Error()}
+		}
+		if err := enc.Encode(resp); err != nil {
+			logrus.Debugf("writing agent response failed: %v", err)
+			return
+		}
+	}
+}
+
+// handle runs a single request against the secrets.
+func (cmd *agentCommand) handle(req agentRequest) (agentResponse, error) {
+	cmd.mu.Lock()
+	defer cmd.mu.Unlock()
+
+	logrus.Debugf("agent request %s %s for %s", req.Op, req.Key, req.File)
+
+	if req.File != cmd.file {
+		return agentResponse{}, fmt.Errorf("the agent serves %s, not %s, unset PONY_AGENT_SOCK to use %s directly", cmd.file, req.File, req.File)
+	}
+
+	switch req.Op {
+	case "get":
+		if err := cmd.refresh(false); err != nil {
+			return agentResponse{}, err
+		}
+		sec, ok := s.Secrets[req.Key]
+		if !ok {
+			return agentResponse{}, fmt.Errorf("secret for key %s does not exist", req.Key)
+		}
+		return agentResponse{Secrets: map[string]secret{req.Key: sec}}, nil
+	case "ls":
+		if err := cmd.refresh(false); err != nil {
+			return agentResponse{}, err
+		}
+		return agentResponse{Secrets: s.Secrets, Recipients: s.Recipients}, nil
+	case "create", "rm":
+		if len(req.Key) == 0 {
+			return agentResponse{}, errors.New("must pass a key")
+		}
+	default:
+		return agentResponse{}, fmt.Errorf("unknown agent request %q", req.Op)
+	}
+
+	// Writes hold the lock on the secrets file for the whole
+	// read-modify-write cycle, like any other pony process.
+	if err := cmd.refresh(true); err != nil {
+		return agentResponse{}, err
+	}
+	defer func() {
+		unlockFile(lock)
+		lock = nil
+	}()
+
+	// Write with the flags of the client.
+	oldHistoryDepth, oldKeyids, oldBackend := historyDepth, keyids, backend
+	historyDepth, keyids, backend = req.HistoryDepth, listFlag{values: req.Keyids, changed: req.KeyidsChanged}, req.Backend
+	defer func() {
+		historyDepth, keyids, backend = oldHistoryDepth, oldKeyids, oldBackend
+	}()
+
+	if req.Op == "create" {
+		if err := s.setSecret(req.Key, req.Value, req.Force, req.Note, req.Tags); err != nil {
+			return agentResponse{}, err
+		}
+	} else {
+		if _, ok := s.Secrets[req.Key]; !ok {
+			return agentResponse{}, fmt.Errorf("secret for key %s does not exist", req.Key)
+		}
+		delete(s.Secrets, req.Key)
+	}
+	if err := writeSecretsFile(cmd.file, s); err != nil {
+		return agentResponse{}, err
+	}
+
+	sum, err := fileSum(cmd.file)
+	if err != nil {
+		return agentResponse{}, err
+	}
+	cmd.sum = sum
+
+	return agentResponse{}, nil
+}
+
+// refresh locks the secrets file and reads it again if it was changed
+// without the agent. The lock is only kept for writes.
+func (cmd *agentCommand) refresh(exclusive bool) (err error) {
+	lock, err = lockFile(cmd.file, exclusive, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if !exclusive || err != nil {
+			unlockFile(lock)
+			lock = nil
+		}
+	}()
+
+	sum, err := fileSum(cmd.file)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(sum[:], cmd.sum[:]) {
+		return nil
+	}
+
+	logrus.Infof("%s changed, reading it again", cmd.file)
+	n, err := readSecretsFile(cmd.file)
+	if err != nil {
+		return err
+	}
+	s = n
+	cmd.sum = sum
+	return nil
+}
+
+// listenAgent listens on the Unix socket and makes sure only the current
+// user can connect to it.
+func listenAgent(socket string) (net.Listener, error) {
+	// Keep the socket in a directory only we can access, so nobody can
+	// connect in the moment before the permissions of the socket are set.
+	dir := filepath.Dir(socket)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating socket directory %s failed: %v", dir, err)
+	}
+	if err := checkSocketDir(dir); err != nil {
+		return nil, err
+	}
+
+	// Remove a socket left behind by an agent that did not exit cleanly.
+	if _, err := os.Stat(socket); err == nil {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("an agent is already listening on %s", socket)
+		}
+		if err := os.Remove(socket); err != nil {
+			return nil, fmt.Errorf("removing stale socket %s failed: %v", socket, err)
+		}
+	}
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %s failed: %v", socket, err)
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("setting permissions on %s failed: %v", socket, err)
+	}
+	return l, nil
+}
+
+// checkSocketDir makes sure the directory of the socket is ours and nobody
+// else can get into it. Otherwise another user could have created it before
+// us to replace the socket and read the secrets sent to the agent.
+func checkSocketDir(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("socket directory %s is not a directory", dir)
+	}
+	if uid, ok := fileOwner(fi); ok && uid != os.Getuid() {
+		return fmt.Errorf("socket directory %s is owned by uid %d, not by you", dir, uid)
+	}
+	if fi.Mode().Perm() != 0700 {
+		return fmt.Errorf("socket directory %s has permissions %#o, it must be 0700", dir, fi.Mode().Perm())
+	}
+	return nil
+}
+
+// fileSum returns the checksum of the contents of the file.
+func fileSum(filename string) ([sha256.Size]byte, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(b), nil
+}
+
+// absFile returns the absolute path of the file with symlinks resolved, so
+// the agent and its clients agree on which secrets file they mean.
+func absFile(filename string) (string, error) {
+	f, err := filepath.Abs(filename)
+	if err != nil {
+		return "", err
+	}
+	if p, err := filepath.EvalSymlinks(f); err == nil {
+		f = p
+	}
+	return f, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+)
+
+// agentCommands are the commands that use the agent when PONY_AGENT_SOCK
+// is set.
+var agentCommands = map[string]bool{
+	"create": true,
+	"get":    true,
+	"ls":     true,
+	"rm":     true,
+}
+
+// agent is the connection to the agent, nil if the secrets file is used
+// directly.
+var agent *agentClient
+
+// agentClient talks to a running pony agent.
+type agentClient struct {
+	conn net.Conn
+	enc  *json.Encoder
+	dec  *json.Decoder
+}
+
+// dialAgent connects to the agent listening on the socket.
+func dialAgent(socket string) (*agentClient, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	return &agentClient{
+		conn: conn,
+		enc:  json.NewEncoder(conn),
+		dec:  json.NewDecoder(conn),
+	}, nil
+}
+
+// newAgentRequest returns a request for the secrets file and with the global
+// flags of this process.
+func newAgentRequest(op, key string) (agentRequest, error) {
+	f, err := absFile(file)
+	if err != nil {
+		return agentRequest{}, err
+	}
+	return agentRequest{
+		Op:            op,
+		Key:           key,
+		File:          f,
+		HistoryDepth:  historyDepth,
+		Keyids:        keyids.values,
+		KeyidsChanged: keyids.changed,
+		Backend:       backend,
+	}, nil
+}
+
+// do sends the request to the agent and waits for the response.
+func (a *agentClient) do(req agentRequest) (agentResponse, error) {
+	var resp agentResponse
+	if err := a.enc.Encode(req); err != nil {
+		return resp, err
+	}
+	if err := a.dec.Decode(&resp); err != nil {
+		return resp, err
+	}
+	if len(resp.Error) > 0 {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// get returns the secret for the key.
+func (a *agentClient) get(key string) (secret, error) {
+	req, err := newAgentRequest("get", key)
+	if err != nil {
+		return secret{}, err
+	}
+	resp, err := a.do(req)
+	return resp.Secrets[key], err
+}
+
+// list returns all the secrets.
+func (a *agentClient) list() (secretFile, error) {
+	req, err := newAgentRequest("ls", "")
+	if err != nil {
+		return secretFile{}, err
+	}
+	resp, err := a.do(req)
+	return secretFile{Secrets: resp.Secrets, Recipients: resp.Recipients}, err
+}
+
+// create sets the value for the key.
+func (a *agentClient) create(key, value string, force bool, note string, tags []string) error {
+	req, err := newAgentRequest("create", key)
+	if err != nil {
+		return err
+	}
+	req.Value, req.Force, req.Note, req.Tags = value, force, note, tags
+	_, err = a.do(req)
+	return err
+}
+
+// remove deletes the secret for the key.
+func (a *agentClient) remove(key string) error {
+	req, err := newAgentRequest("rm", key)
+	if err != nil {
+		return err
+	}
+	_, err = a.do(req)
+	return err
+}
+
+// Close closes the connection to the agent.
+func (a *agentClient) Close() error {
+	return a.conn.Close()
+}
@@ -0,0 +1,13 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package main
+
+import (
+	"os"
+)
+
+// The owner of a file is not known on this platform.
+func fileOwner(fi os.FileInfo) (int, bool) {
+	return 0, false
+}
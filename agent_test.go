@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startAgent runs an agent for the secrets file and returns a client
+// connected to it and the socket. The returned channel is closed when the
+// agent exits.
+func startAgent(t *testing.T, idleTimeout time.Duration) (*agentClient, string, chan struct{}) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "pony", "agent.sock")
+
+	done := make(chan struct{})
+	cmd := &agentCommand{socket: socket, idleTimeout: idleTimeout}
+	go func() {
+		defer close(done)
+		if err := cmd.Run(context.Background(), nil); err != nil {
+			t.Errorf("agent failed: %v", err)
+		}
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		a, err := dialAgent(socket)
+		if err == nil {
+			t.Cleanup(func() { a.Close() })
+			return a, socket, done
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("connecting to the agent failed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitAgent waits for the agent to exit.
+func waitAgent(t *testing.T, done chan struct{}, timeout time.Duration) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatalf("the agent did not exit within %s", timeout)
+	}
+}
+
+func TestListenAgentPermissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "pony")
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := listenAgent(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0700 {
+		t.Fatalf("expected the socket directory to be 0700, got %#o", perm)
+	}
+	fi, err = os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		t.Fatalf("expected %s to be a socket, got %s", socket, fi.Mode())
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Fatalf("expected the socket to be 0600, got %#o", perm)
+	}
+
+	// A directory others can get into is refused.
+	open := filepath.Join(t.TempDir(), "open")
+	if err := os.Mkdir(open, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(open, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenAgent(filepath.Join(open, "agent.sock")); err == nil || !strings.Contains(err.Error(), "it must be 0700") {
+		t.Fatalf("expected the socket directory to be refused, got %v", err)
+	}
+}
+
+func TestAgentRoundTrip(t *testing.T) {
+	id, _ := newAgeIdentity(t, t.TempDir(), "key.txt")
+	setupAgeStore(t, id)
+	a, _, done := startAgent(t, time.Second)
+
+	sec, err := a.get("com.github.jessfraz.token")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if sec.Value != "s3cret" {
+		t.Fatalf("expected s3cret, got %q", sec.Value)
+	}
+
+	if err := a.create("com.example.api", "v1", false, "a note", []string{"work"}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if err := a.create("com.example.api", "v2", false, "", nil); err == nil {
+		t.Fatal("expected create to refuse to overwrite without force")
+	}
+	if err := a.create("com.example.api", "v2", true, "", nil); err != nil {
+		t.Fatalf("create with force failed: %v", err)
+	}
+
+	l, err := a.list()
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(l.Secrets) != 2 {
+		t.Fatalf("expected 2 secrets, got %d", len(l.Secrets))
+	}
+	if got := l.Secrets["com.example.api"]; got.Value != "v2" || got.Note != "a note" || got.Version != 2 {
+		t.Fatalf("unexpected secret %+v", got)
+	}
+
+	if err := a.remove("com.github.jessfraz.token"); err != nil {
+		t.Fatalf("rm failed: %v", err)
+	}
+	if _, err := a.get("com.github.jessfraz.token"); err == nil {
+		t.Fatal("expected get of a removed secret to fail")
+	}
+	if err := a.remove("com.github.jessfraz.token"); err == nil {
+		t.Fatal("expected rm of a removed secret to fail")
+	}
+
+	// The agent wrote the changes to the secrets file.
+	waitAgent(t, done, 5*time.Second)
+	n, err := readSecretsFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := n.Secrets["com.github.jessfraz.token"]; ok {
+		t.Fatal("expected the removed secret to be gone from the secrets file")
+	}
+	if got := n.Secrets["com.example.api"].Value; got != "v2" {
+		t.Fatalf("expected v2 in the secrets file, got %q", got)
+	}
+}
+
+func TestAgentIdleTimeout(t *testing.T) {
+	id, _ := newAgeIdentity(t, t.TempDir(), "key.txt")
+	setupAgeStore(t, id)
+	a, socket, done := startAgent(t, 200*time.Millisecond)
+
+	// Requests keep the agent alive.
+	for i := 0; i < 5; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if _, err := a.get("com.github.jessfraz.token"); err != nil {
+			t.Fatalf("get failed after %d requests: %v", i, err)
+		}
+	}
+
+	waitAgent(t, done, 5*time.Second)
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Fatalf("expected the agent to remove its socket, got %v", err)
+	}
+	if _, err := net.Dial("unix", socket); err == nil {
+		t.Fatal("expected the agent to stop listening")
+	}
+}
+
+func TestAgentRefusesOtherFile(t *testing.T) {
+	id, _ := newAgeIdentity(t, t.TempDir(), "key.txt")
+	setupAgeStore(t, id)
+	a, _, done := startAgent(t, time.Second)
+
+	req, err := newAgentRequest("get", "com.github.jessfraz.token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.File = filepath.Join(t.TempDir(), "other")
+	if _, err := a.do(req); err == nil || !strings.Contains(err.Error(), "the agent serves") {
+		t.Fatalf("expected the agent to refuse another secrets file, got %v", err)
+	}
+
+	req.Op, req.Value = "create", "v"
+	if _, err := a.do(req); err == nil || !strings.Contains(err.Error(), "the agent serves") {
+		t.Fatalf("expected the agent to refuse to write another secrets file, got %v", err)
+	}
+
+	waitAgent(t, done, 5*time.Second)
+}
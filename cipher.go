@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jessfraz/pony/age"
+	"github.com/jessfraz/pony/gpg"
+)
+
+const defaultBackend = "gpg"
+
+// cipher is the interface for the encryption backends used for the secrets
+// filestore.
+type cipher interface {
+	// Encrypt the plaintext to the given recipients.
+	Encrypt(b []byte, recipients []string) ([]byte, error)
+	// Decrypt the ciphertext.
+	Decrypt(b []byte) ([]byte, error)
+	// DefaultRecipients returns who Encrypt encrypts to when no recipients
+	// are given, the key of the current identity.
+	DefaultRecipients() ([]string, error)
+}
+
+// ciphers holds the available encryption backends by the name that is
+// recorded in the secrets filestore.
+var ciphers = map[string]cipher{
+	"gpg": gpgCipher{},
+	"age": ageCipher{},
+}
+
+// getCipher returns the encryption backend for the given name.
+func getCipher(name string) (cipher, error) {
+	c, ok := ciphers[name]
+	if !ok {
+		names := []string{}
+		for n := range ciphers {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown backend %q, must be one of %s", name, strings.Join(names, ", "))
+	}
+	return c, nil
+}
+
+type gpgCipher struct{}
+
+func (gpgCipher) Encrypt(b []byte, recipients []string) ([]byte, error) {
+	return gpg.EncryptBytes(b, recipients...)
+}
+
+func (gpgCipher) Decrypt(b []byte) ([]byte, error) {
+	return gpg.DecryptBytes(b)
+}
+
+func (gpgCipher) DefaultRecipients() ([]string, error) {
+	key, err := gpg.DefaultKey()
+	if err != nil {
+		return nil, err
+	}
+	return []string{key}, nil
+}
+
+type ageCipher struct{}
+
+func (ageCipher) Encrypt(b []byte, recipients []string) ([]byte, error) {
+	return age.Encrypt(b, recipients...)
+}
+
+func (ageCipher) Decrypt(b []byte) ([]byte, error) {
+	return age.Decrypt(b)
+}
+
+func (ageCipher) DefaultRecipients() ([]string, error) {
+	return age.Recipients()
+}
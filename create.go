@@ -9,37 +9,83 @@ import (
 
 const createHelp = `Create a secret.`
 
+const createLongHelp = createHelp + `
+
+If VALUE is not given or is "-", it is read from stdin, or prompted for without
+echoing it when stdin is a terminal. This keeps the value out of your shell
+history and ps output.`
+
 func (cmd *createCommand) Name() string      { return "create" }
-func (cmd *createCommand) Args() string      { return "[OPTIONS] KEY VALUE" }
+func (cmd *createCommand) Args() string      { return "[OPTIONS] KEY [VALUE]" }
 func (cmd *createCommand) ShortHelp() string { return createHelp }
-func (cmd *createCommand) LongHelp() string  { return createHelp }
+func (cmd *createCommand) LongHelp() string  { return createLongHelp }
 func (cmd *createCommand) Hidden() bool      { return false }
 
 func (cmd *createCommand) Register(fs *flag.FlagSet) {
 	fs.BoolVar(&cmd.force, "force", false, "force overwrite existing value")
 	fs.BoolVar(&cmd.force, "f", false, "force overwrite existing value")
+	fs.StringVar(&cmd.note, "note", "", "note about what the secret is for")
+	fs.Var(&cmd.tags, "tag", "tags for the secret, comma separated or repeated")
+	fs.StringVar(&cmd.fromFile, "from-file", "", "read the value from a file")
+	fs.BoolVar(&cmd.generate, "generate", false, "generate a random value instead of passing one, see the generate command for the options")
+	cmd.generator.register(fs)
 }
 
 type createCommand struct {
 	force bool
+	note  string
+	tags  listFlag
+
+	fromFile string
+
+	generate  bool
+	generator generator
 }
 
 func (cmd *createCommand) Run(ctx context.Context, args []string) error {
-	if len(args) < 2 {
-		return errors.New("must pass a key and value")
+	if cmd.generate {
+		if len(args) > 1 || len(cmd.fromFile) > 0 {
+			return errors.New("pass either a value, --from-file, or --generate, not more than one")
+		}
+		return (&generateCommand{
+			force:     cmd.force,
+			note:      cmd.note,
+			tags:      cmd.tags,
+			generator: cmd.generator,
+		}).Run(ctx, args)
+	}
+
+	if len(args) < 1 {
+		return errors.New("must pass a key")
+	}
+	key := args[0]
+
+	// Get the value from the arguments or read it.
+	if len(args) > 1 && args[1] != "-" && len(cmd.fromFile) > 0 {
+		return errors.New("pass either a value or --from-file, not both")
+	}
+	var value string
+	if len(args) > 1 && args[1] != "-" {
+		value = args[1]
+	} else {
+		var err error
+		value, err = readValue(cmd.fromFile)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Check if we are updating.
 	verb := "Added"
-	_, isUpdating := s.Secrets[args[0]]
-	if isUpdating {
+	if _, err := getSecret(key); err == nil {
 		verb = "Updated"
 	}
 
 	// Add the key value pair to secrets.
-	key, value := args[0], args[1]
-	s.setKeyValue(key, value, cmd.force)
+	if err := s.setKeyValue(key, value, cmd.force, cmd.note, cmd.tags.values); err != nil {
+		return err
+	}
 
-	fmt.Printf("%s %s %s to secrets\n", verb, key, value)
+	fmt.Printf("%s %s to secrets\n", verb, key)
 	return nil
 }
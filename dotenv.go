@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// envName maps a secret key to an environment variable name, after removing
+// the prefix. For example com.github.jessfraz.token becomes
+// COM_GITHUB_JESSFRAZ_TOKEN.
+func envName(key, prefix string) string {
+	key = strings.TrimPrefix(key, prefix)
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// dotenvName maps a secret key to the name of a variable in a dotenv file,
+// after removing the prefix. Keys with mixed case were imported with the name
+// as is and keep it, others are made into a name with envName.
+func dotenvName(key, prefix string) string {
+	name := strings.TrimPrefix(key, prefix)
+	if isMixedCase(name) && isEnvName(name) {
+		return name
+	}
+	return envName(key, prefix)
+}
+
+// dotenvKey returns the key a variable of a dotenv file is saved as under the
+// prefix, so that dotenvName gives back the same name. DATABASE_URL becomes
+// PREFIX.database_url and names with mixed case like apiKey are kept as is.
+func dotenvKey(name, prefix string) (string, error) {
+	key := prefix + name
+	if !isMixedCase(name) {
+		key = prefix + strings.ToLower(name)
+	}
+	if dotenvName(key, prefix) != name {
+		return "", fmt.Errorf("variable %s would be exported as %s, only names in upper or mixed case can be imported", name, dotenvName(key, prefix))
+	}
+	return key, nil
+}
+
+// isMixedCase checks if the string has both upper and lower case letters.
+func isMixedCase(s string) bool {
+	return strings.ToLower(s) != s && strings.ToUpper(s) != s
+}
+
+// dotenvQuote quotes the value for a dotenv file if it needs to be.
+func dotenvQuote(value string) string {
+	if len(value) > 0 && !strings.ContainsAny(value, " \t\r\n\"'\\$#=`") {
+		return value
+	}
+
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"$", `\$`,
+		"`", "\\`",
+		"\n", `\n`,
+		"\r", `\r`,
+		"\t", `\t`,
+	)
+	return `"` + r.Replace(value) + `"`
+}
+
+// dotenvVar is a variable read from a dotenv file.
+type dotenvVar struct {
+	Name  string
+	Value string
+}
+
+// parseDotenv parses the variables of a dotenv file. It supports comments,
+// an optional export in front of the name, single quoted values that are
+// taken literally, and double quoted values with escapes. Quoted values can
+// span multiple lines. Variables are not expanded.
+func parseDotenv(content string) ([]dotenvVar, error) {
+	content = strings.Replace(content, "\r\n", "\n", -1)
+
+	vars := []dotenvVar{}
+	for pos := 0; pos < len(content); {
+		line := strings.Count(content[:pos], "\n") + 1
+		end := lineEnd(content, pos)
+		l := strings.TrimSpace(content[pos:end])
+		if len(l) == 0 || strings.HasPrefix(l, "#") {
+			pos = end + 1
+			continue
+		}
+
+		eq := strings.IndexByte(content[pos:end], '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected NAME=VALUE", line)
+		}
+		name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(content[pos:pos+eq]), "export "))
+		if !isEnvName(name) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", line, name)
+		}
+		pos += eq + 1
+		for pos < len(content) && (content[pos] == ' ' || content[pos] == '\t') {
+			pos++
+		}
+
+		var value string
+		if pos < len(content) && (content[pos] == '"' || content[pos] == '\'') {
+			// Quoted values can span multiple lines, only a comment may
+			// follow them.
+			v, n, err := parseDotenvQuoted(content[pos:], content[pos])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			value = v
+			pos += n
+			end = lineEnd(content, pos)
+			if trailing := strings.TrimSpace(content[pos:end]); len(trailing) > 0 && !strings.HasPrefix(trailing, "#") {
+				return nil, fmt.Errorf("line %d: unexpected %q after the value of %s", line, trailing, name)
+			}
+		} else {
+			end = lineEnd(content, pos)
+			value = content[pos:end]
+			if c := strings.Index(value, " #"); c >= 0 {
+				value = value[:c]
+			}
+			value = strings.TrimSpace(value)
+		}
+
+		vars = append(vars, dotenvVar{Name: name, Value: value})
+		pos = end + 1
+	}
+
+	return vars, nil
+}
+
+// lineEnd returns the position of the end of the line at pos.
+func lineEnd(s string, pos int) int {
+	if i := strings.IndexByte(s[pos:], '\n'); i >= 0 {
+		return pos + i
+	}
+	return len(s)
+}
+
+// parseDotenvQuoted parses the quoted value at the start of s and returns
+// the value and the number of bytes it took up. Escapes are only supported
+// in double quoted values.
+func parseDotenvQuoted(s string, quote byte) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && quote == '"' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("missing closing %c", quote)
+}
+
+// isEnvName checks if the name is a valid environment variable name.
+func isEnvName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return len(name) > 0
+}
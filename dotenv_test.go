@@ -0,0 +1,146 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDotenvRoundTrip(t *testing.T) {
+	oldSecrets := s
+	t.Cleanup(func() { s = oldSecrets })
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.env")
+	out := filepath.Join(dir, "out.env")
+
+	content := `# database
+DATABASE_URL=postgres://app:secret@db/app
+export API_KEY='KSUIIUEJDMSDBSDJFOFR'
+apiSecret="with \"quotes\" and $dollars"
+Mixed_Case_2=plain
+CERT="-----BEGIN CERTIFICATE-----
+MIIBszCCAVmgAwIBAgIU
+-----END CERTIFICATE-----
+"
+ESCAPED="line one\nline two\ttabbed"
+EMPTY=
+SPACES="  padded  "
+`
+	if err := ioutil.WriteFile(in, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	want, err := parseDotenv(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := importDotenv(&importCommand{prefix: "com.myapp.prod"}, in)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	s = secretFile{Secrets: map[string]secret{}}
+	keys := []string{}
+	for _, e := range entries {
+		s.Secrets[e.Key] = secret{Value: e.Value}
+		keys = append(keys, e.Key)
+	}
+	sort.Strings(keys)
+
+	if err := exportDotenv(&exportCommand{format: "dotenv", prefix: "com.myapp.prod.", output: out}, keys); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseDotenv(string(b))
+	if err != nil {
+		t.Fatalf("parsing the export failed: %v\n%s", err, b)
+	}
+
+	sortVars := func(vars []dotenvVar) {
+		sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	}
+	sortVars(want)
+	sortVars(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip drifted\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestDotenvKey(t *testing.T) {
+	testCases := []struct {
+		name    string
+		key     string
+		wantErr string
+	}{
+		{name: "DATABASE_URL", key: "p.database_url"},
+		{name: "_PRIVATE2", key: "p._private2"},
+		{name: "apiKey", key: "p.apiKey"},
+		{name: "Mixed_Case", key: "p.Mixed_Case"},
+		{name: "lower", wantErr: "would be exported as LOWER"},
+		{name: "port", wantErr: "would be exported as PORT"},
+	}
+
+	for _, tc := range testCases {
+		key, err := dotenvKey(tc.name, "p.")
+		if len(tc.wantErr) > 0 {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if key != tc.key {
+			t.Errorf("%s: expected key %s, got %s", tc.name, tc.key, key)
+		}
+		if name := dotenvName(key, "p."); name != tc.name {
+			t.Errorf("%s: exported back as %s", tc.name, name)
+		}
+	}
+}
+
+func TestParseDotenvInvalidName(t *testing.T) {
+	testCases := []struct {
+		content string
+		wantErr string
+	}{
+		{content: "A=1\nA.B=2\n", wantErr: `line 2: invalid variable name "A.B"`},
+		{content: "# comment\n\nexport 1A=1\n", wantErr: `line 3: invalid variable name "1A"`},
+		{content: "A=\"multi\nline\"\nB-C=1\n", wantErr: `line 3: invalid variable name "B-C"`},
+	}
+
+	for _, tc := range testCases {
+		_, err := parseDotenv(tc.content)
+		if err == nil || err.Error() != tc.wantErr {
+			t.Errorf("%q: expected error %q, got %v", tc.content, tc.wantErr, err)
+		}
+	}
+}
+
+func TestImportDotenvSkipsNames(t *testing.T) {
+	in := filepath.Join(t.TempDir(), "in.env")
+	if err := ioutil.WriteFile(in, []byte("DATABASE_URL=postgres://db/app\nport=5432\napiKey=abc\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := importDotenv(&importCommand{prefix: "p"}, in)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	want := []importEntry{
+		{Key: "p.database_url", Value: "postgres://db/app"},
+		{},
+		{Key: "p.apiKey", Value: "abc"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("expected %+v, got %+v", want, entries)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const editHelp = `Edit the value of a secret in your editor.`
+
+const editLongHelp = editHelp + `
+
+The value is written to a temporary file in a private directory, on a tmpfs
+if one is available, and opened with $VISUAL or $EDITOR. The secret is only
+updated if the editor exits successfully and the value changed. The temporary
+file is overwritten before it is deleted.`
+
+func (cmd *editCommand) Name() string      { return "edit" }
+func (cmd *editCommand) Args() string      { return "[OPTIONS] KEY" }
+func (cmd *editCommand) ShortHelp() string { return editHelp }
+func (cmd *editCommand) LongHelp() string  { return editLongHelp }
+func (cmd *editCommand) Hidden() bool      { return false }
+
+func (cmd *editCommand) Register(fs *flag.FlagSet) {}
+
+type editCommand struct{}
+
+func (cmd *editCommand) Run(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return errors.New("must pass a key")
+	}
+
+	key := args[0]
+	sec, ok := s.Secrets[key]
+	if !ok {
+		return fmt.Errorf("secret for key %s does not exist", key)
+	}
+
+	dir, err := ioutil.TempDir(tempDir(), "pony-edit-")
+	if err != nil {
+		return fmt.Errorf("creating temporary directory failed: %v", err)
+	}
+	defer func() {
+		if err := shredDir(dir); err != nil {
+			logrus.Warnf("removing temporary directory %s failed: %v", dir, err)
+		}
+	}()
+
+	// Name the file after the key so the editor can pick the syntax
+	// highlighting from the extension.
+	tmp := filepath.Join(dir, strings.Replace(key, string(filepath.Separator), "_", -1))
+	if err := ioutil.WriteFile(tmp, []byte(sec.Value), 0600); err != nil {
+		return fmt.Errorf("writing temporary file failed: %v", err)
+	}
+
+	editor := strings.Fields(getEditor())
+	c := exec.Command(editor[0], append(editor[1:], tmp)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("running %s failed, not saving: %v", editor[0], err)
+	}
+
+	b, err := ioutil.ReadFile(tmp)
+	if err != nil {
+		return fmt.Errorf("reading temporary file failed: %v", err)
+	}
+
+	// Most editors add a newline at the end of the file.
+	value := string(b)
+	if !strings.HasSuffix(sec.Value, "\n") {
+		value = strings.TrimSuffix(strings.TrimSuffix(value, "\n"), "\r")
+	}
+
+	if value == sec.Value {
+		fmt.Printf("No changes to %s\n", key)
+		return nil
+	}
+
+	if err := s.setKeyValue(key, value, true, "", nil); err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated %s to secrets\n", key)
+	return nil
+}
+
+// getEditor returns the editor command set by the user.
+func getEditor() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if e := strings.TrimSpace(os.Getenv(env)); len(e) > 0 {
+			return e
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// tempDir returns the directory for temporary files holding plaintext,
+// preferring tmpfs mounts that are never written to disk.
+func tempDir() string {
+	for _, dir := range []string{os.Getenv("XDG_RUNTIME_DIR"), "/dev/shm"} {
+		if len(dir) == 0 {
+			continue
+		}
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir
+		}
+	}
+	return os.TempDir()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// envelopeMagic starts the unencrypted header line of the secrets filestore.
+const envelopeMagic = "pony-secrets"
+
+// envelope is the unencrypted header of the secrets filestore. It records
+// the format version and the backend so we know how to read the file before
+// decrypting it. The header is a single line like:
+//
+//	pony-secrets v3 gpg
+//
+// followed by the base64 encoded ciphertext.
+type envelope struct {
+	// Version is the format version of the file. It is 0 for files written
+	// before the header existed, their version is only known once they are
+	// decrypted.
+	Version int
+	// Backend is the name of the encryption backend.
+	Backend string
+}
+
+// parseEnvelope splits the secrets filestore into its header and the base64
+// decoded ciphertext.
+func parseEnvelope(b []byte) (envelope, []byte, error) {
+	var (
+		e    envelope
+		body []byte
+	)
+
+	if bytes.HasPrefix(b, []byte(envelopeMagic+" ")) {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			return e, nil, fmt.Errorf("invalid %s header: missing ciphertext", envelopeMagic)
+		}
+		fields := strings.Fields(string(b[:i]))
+		if len(fields) != 3 || !strings.HasPrefix(fields[1], "v") {
+			return e, nil, fmt.Errorf("invalid %s header: %q", envelopeMagic, string(b[:i]))
+		}
+		v, err := strconv.Atoi(strings.TrimPrefix(fields[1], "v"))
+		if err != nil {
+			return e, nil, fmt.Errorf("invalid %s header version %q: %v", envelopeMagic, fields[1], err)
+		}
+		e.Version = v
+		e.Backend = fields[2]
+		body = b[i+1:]
+	} else {
+		// Files written before the header existed only hold gpg
+		// ciphertext.
+		e.Backend = defaultBackend
+		body = b
+	}
+
+	out, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(body)))
+	if err != nil {
+		return e, nil, fmt.Errorf("base64 decoding ciphertext failed: %v", err)
+	}
+	return e, out, nil
+}
+
+// encode returns the header followed by the base64 encoded ciphertext.
+func (e envelope) encode(ciphertext []byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s v%d %s\n", envelopeMagic, e.Version, e.Backend)
+	b.WriteString(base64.StdEncoding.EncodeToString(ciphertext))
+	return b.Bytes()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvelope(t *testing.T) {
+	ciphertext := []byte("not really encrypted")
+	b64 := base64.StdEncoding.EncodeToString(ciphertext)
+
+	testCases := []struct {
+		name     string
+		file     string
+		expected envelope
+		wantErr  string
+	}{
+		{
+			name:     "header gpg",
+			file:     "pony-secrets v3 gpg\n" + b64,
+			expected: envelope{Version: 3, Backend: "gpg"},
+		},
+		{
+			name:     "header age",
+			file:     "pony-secrets v2 age\n" + b64 + "\n",
+			expected: envelope{Version: 2, Backend: "age"},
+		},
+		{
+			name:     "header newer version",
+			file:     "pony-secrets v99 gpg\n" + b64,
+			expected: envelope{Version: 99, Backend: "gpg"},
+		},
+		{
+			name:     "plain gpg",
+			file:     b64,
+			expected: envelope{Backend: "gpg"},
+		},
+		{
+			name:    "missing ciphertext",
+			file:    "pony-secrets v3 gpg",
+			wantErr: "missing ciphertext",
+		},
+		{
+			name:    "missing backend",
+			file:    "pony-secrets v3\n" + b64,
+			wantErr: "invalid pony-secrets header",
+		},
+		{
+			name:    "missing v",
+			file:    "pony-secrets 3 gpg\n" + b64,
+			wantErr: "invalid pony-secrets header",
+		},
+		{
+			name:    "invalid version",
+			file:    "pony-secrets vthree gpg\n" + b64,
+			wantErr: "invalid pony-secrets header version",
+		},
+		{
+			name:    "invalid base64",
+			file:    "pony-secrets v3 gpg\n!!!",
+			wantErr: "base64 decoding ciphertext failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		e, body, err := parseEnvelope([]byte(tc.file))
+		if len(tc.wantErr) > 0 {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if e != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expected, e)
+		}
+		if string(body) != string(ciphertext) {
+			t.Errorf("%s: expected ciphertext %q, got %q", tc.name, ciphertext, body)
+		}
+	}
+}
+
+func TestEnvelopeEncode(t *testing.T) {
+	ciphertext := []byte{0x00, 0x01, 0xfe, 0xff}
+	e := envelope{Version: formatVersion, Backend: "age"}
+
+	b := e.encode(ciphertext)
+	expected := fmt.Sprintf("pony-secrets v%d age\n", formatVersion) + base64.StdEncoding.EncodeToString(ciphertext)
+	if string(b) != expected {
+		t.Fatalf("expected %q, got %q", expected, b)
+	}
+
+	parsed, body, err := parseEnvelope(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed != e {
+		t.Fatalf("expected %+v, got %+v", e, parsed)
+	}
+	if string(body) != string(ciphertext) {
+		t.Fatalf("expected ciphertext %q, got %q", ciphertext, body)
+	}
+}
+
+func TestReadSecretsFileRefuses(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString([]byte("ciphertext"))
+
+	testCases := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{
+			name:    "unknown backend",
+			file:    fmt.Sprintf("pony-secrets v%d rot13\n", formatVersion) + b64,
+			wantErr: `unknown backend "rot13", must be one of age, gpg`,
+		},
+		{
+			name:    "newer version",
+			file:    fmt.Sprintf("pony-secrets v%d gpg\n", formatVersion+1) + b64,
+			wantErr: fmt.Sprintf("written by a newer version of pony (format v%d, this version supports up to v%d)", formatVersion+1, formatVersion),
+		},
+	}
+
+	for _, tc := range testCases {
+		p := filepath.Join(t.TempDir(), "pony")
+		if err := ioutil.WriteFile(p, []byte(tc.file), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := readSecretsFile(p)
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
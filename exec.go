@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+const execHelp = `Run a command with secrets in its environment.`
+
+const execLongHelp = execHelp + `
+
+Secrets are only exported to the environment of the command and never written
+to disk. Use --map to export single secrets and --prefix to export every
+secret in a namespace. For a prefix like com.aws.prod the key
+com.aws.prod.access_key_id is exported as AWS_ACCESS_KEY_ID.
+
+Example:
+
+  pony exec --map GITHUB_TOKEN=com.github.jessfraz.token --prefix com.aws.prod -- terraform apply`
+
+func (cmd *execCommand) Name() string      { return "exec" }
+func (cmd *execCommand) Args() string      { return "[OPTIONS] -- COMMAND [ARG...]" }
+func (cmd *execCommand) ShortHelp() string { return execHelp }
+func (cmd *execCommand) LongHelp() string  { return execLongHelp }
+func (cmd *execCommand) Hidden() bool      { return false }
+
+func (cmd *execCommand) Register(fs *flag.FlagSet) {
+	fs.Var(&cmd.maps, "map", "export a secret as ENV_NAME=key, comma separated or repeated")
+	fs.Var(&cmd.prefixes, "prefix", "export all the secrets under a key prefix, comma separated or repeated")
+	fs.StringVar(&cmd.envPrefix, "env-prefix", "", "prefix for the environment variable names of --prefix secrets, defaults to the name after the top level domain in the key prefix, like AWS_ for com.aws.prod")
+}
+
+type execCommand struct {
+	maps      listFlag
+	prefixes  listFlag
+	envPrefix string
+}
+
+func (cmd *execCommand) Run(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return errors.New("must pass a command to run")
+	}
+
+	env, err := cmd.environ()
+	if err != nil {
+		return err
+	}
+
+	// We already decrypted the secrets, do not hold the lock on the secrets
+	// file for as long as the command runs.
+	if err := unlockFile(lock); err != nil {
+		return err
+	}
+	lock = nil
+
+	c := exec.Command(args[0], args[1:]...)
+	c.Env = append(os.Environ(), env...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	// Forward signals to the command. Signals from the terminal already
+	// reach the command, forwarding them would deliver them twice.
+	signals := make(chan os.Signal, 1)
+	if len(forwardSignals) > 0 {
+		// Notify without signals would relay all of them.
+		signal.Notify(signals, forwardSignals...)
+		defer signal.Stop(signals)
+	}
+	terminal := make(chan os.Signal, 1)
+	signal.Notify(terminal, terminalSignals...)
+	defer signal.Stop(terminal)
+
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("starting %s failed: %v", args[0], err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				c.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if err := c.Wait(); err != nil {
+		// Exit with the same code as the command, or like a shell would if
+		// it was killed by a signal.
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+				os.Exit(128 + int(ws.Signal()))
+			}
+			if exitErr.ExitCode() > 0 {
+				os.Exit(exitErr.ExitCode())
+			}
+		}
+		return fmt.Errorf("running %s failed: %v", args[0], err)
+	}
+
+	return nil
+}
+
+// environ returns the environment variables for the selected secrets.
+func (cmd *execCommand) environ() ([]string, error) {
+	vars := map[string]string{}
+
+	for _, prefix := range cmd.prefixes.values {
+		prefix = strings.TrimSuffix(prefix, ".") + "."
+		envPrefix := cmd.envPrefix
+		if len(envPrefix) == 0 {
+			envPrefix = defaultEnvPrefix(prefix)
+		}
+
+		found := false
+		for key, sec := range s.Secrets {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+			vars[envPrefix+envName(key, prefix)] = sec.Value
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("no secrets found with the prefix %s", prefix)
+		}
+	}
+
+	for _, m := range cmd.maps.values {
+		i := strings.Index(m, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("invalid --map %q, must be ENV_NAME=key", m)
+		}
+		name, key := m[:i], m[i+1:]
+		sec, ok := s.Secrets[key]
+		if !ok {
+			return nil, fmt.Errorf("secret for key %s does not exist", key)
+		}
+		vars[name] = sec.Value
+	}
+
+	env := []string{}
+	for name, value := range vars {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+// defaultEnvPrefix returns the environment variable name prefix for a key
+// prefix, which is the name after the top level domain. For example AWS_ for
+// com.aws.prod.
+func defaultEnvPrefix(prefix string) string {
+	parts := strings.Split(strings.Trim(prefix, "."), ".")
+	name := parts[0]
+	if len(parts) > 1 {
+		name = parts[1]
+	}
+	return envName(name, "") + "_"
+}
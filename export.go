@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const exportHelp = `Export secrets to other formats.`
+
+const exportLongHelp = exportHelp + `
+
+Formats:
+
+  json, csv, dotenv   plain text, requires --plaintext, use --prefix to export
+                      com.myapp.prod.database_url as DATABASE_URL to dotenv
+  kdbx                a KeePass database, asks for a new master password
+  pass                a pass password store encrypted with gpg, defaults to
+                      $PASSWORD_STORE_DIR or ~/.password-store
+
+The format can be given as the first argument or with --format. A key like
+com.github.jessfraz is saved in pass as github.com/jessfraz, which pony import
+pass maps back to the same key.`
+
+func (cmd *exportCommand) Name() string      { return "export" }
+func (cmd *exportCommand) Args() string      { return "[OPTIONS] [FORMAT]" }
+func (cmd *exportCommand) ShortHelp() string { return exportHelp }
+func (cmd *exportCommand) LongHelp() string  { return exportLongHelp }
+func (cmd *exportCommand) Hidden() bool      { return false }
+
+func (cmd *exportCommand) Register(fs *flag.FlagSet) {
+	cmd.fs = fs
+	fs.StringVar(&cmd.format, "format", "", "format to export: "+strings.Join(exportFormats(), ", "))
+	fs.StringVar(&cmd.filter, "filter", "", "filter secrets keys by a regular expression")
+	fs.StringVar(&cmd.filter, "f", "", "filter secrets keys by a regular expression")
+	fs.Var(&cmd.tags, "tag", "only export secrets with this tag, comma separated or repeated")
+	fs.StringVar(&cmd.prefix, "prefix", "", "only export the secrets under the key prefix, it is left out of dotenv names")
+	fs.BoolVar(&cmd.plaintext, "plaintext", false, "allow exporting to formats that are not encrypted")
+	fs.StringVar(&cmd.output, "o", "", "file or directory to write the export to, defaults to stdout")
+	fs.StringVar(&cmd.output, "output", "", "file or directory to write the export to, defaults to stdout")
+	fs.Var(&cmd.to, "to", "gpg keyids to encrypt the pass store to, defaults to the recipients of the secrets file or the default gpg key")
+	fs.BoolVar(&cmd.force, "force", false, "overwrite existing files")
+}
+
+type exportCommand struct {
+	format    string
+	filter    string
+	tags      listFlag
+	prefix    string
+	plaintext bool
+	output    string
+	to        listFlag
+	force     bool
+
+	// fs is the flag set the command was registered with.
+	fs *flag.FlagSet
+}
+
+// exporter writes the secrets with the keys.
+type exporter func(cmd *exportCommand, keys []string) error
+
+// exporters are the supported formats.
+var exporters = map[string]exporter{
+	"csv":    exportRecords,
+	"dotenv": exportDotenv,
+	"json":   exportRecords,
+	"kdbx":   exportKDBX,
+	"pass":   exportPass,
+}
+
+// encryptedExports are the formats that do not need --plaintext.
+var encryptedExports = map[string]bool{
+	"kdbx": true,
+	"pass": true,
+}
+
+func (cmd *exportCommand) Run(ctx context.Context, args []string) error {
+	args, err := parseTrailingFlags(cmd.fs, &exportCommand{}, args)
+	if err != nil {
+		return err
+	}
+
+	if len(cmd.format) == 0 {
+		if len(args) < 1 {
+			return errors.New("must pass the format to export")
+		}
+		cmd.format = args[0]
+	}
+	exp, ok := exporters[cmd.format]
+	if !ok {
+		return fmt.Errorf("unknown export format %q, must be one of %s", cmd.format, strings.Join(exportFormats(), ", "))
+	}
+	if !encryptedExports[cmd.format] && !cmd.plaintext {
+		return fmt.Errorf("%s exports are not encrypted, pass --plaintext if you really want to write your secrets in plain text", cmd.format)
+	}
+
+	if len(cmd.prefix) > 0 {
+		cmd.prefix = strings.TrimSuffix(cmd.prefix, ".") + "."
+	}
+	keys := []string{}
+	for _, key := range filterKeys(cmd.filter, cmd.tags.values) {
+		if strings.HasPrefix(key, cmd.prefix) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return errors.New("no secrets to export")
+	}
+
+	return exp(cmd, keys)
+}
+
+// exportRecords writes the secrets in one of the plain text output formats.
+func exportRecords(cmd *exportCommand, keys []string) error {
+	if _, err := os.Stat(cmd.output); err == nil && !cmd.force {
+		return fmt.Errorf("%s already exists, use `--force` to overwrite it", cmd.output)
+	}
+
+	records := []secretRecord{}
+	for _, key := range keys {
+		records = append(records, newSecretRecord(key, s.Secrets[key], s.Secrets[key].Value))
+	}
+
+	var buf bytes.Buffer
+	if err := writeRecords(&buf, cmd.format, records, false); err != nil {
+		return err
+	}
+	return writeOutput(cmd.output, buf.Bytes())
+}
+
+// exportDotenv writes the secrets as a dotenv file. The names are made from
+// the keys without the prefix.
+func exportDotenv(cmd *exportCommand, keys []string) error {
+	if _, err := os.Stat(cmd.output); err == nil && !cmd.force {
+		return fmt.Errorf("%s already exists, use `--force` to overwrite it", cmd.output)
+	}
+
+	var buf bytes.Buffer
+	names := map[string]string{}
+	for _, key := range keys {
+		name := dotenvName(key, cmd.prefix)
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("secrets for keys %s and %s both export as %s", other, key, name)
+		}
+		names[name] = key
+		fmt.Fprintf(&buf, "%s=%s\n", name, dotenvQuote(s.Secrets[key].Value))
+	}
+	return writeOutput(cmd.output, buf.Bytes())
+}
+
+// exportFormats returns the names of the supported formats.
+func exportFormats() []string {
+	formats := []string{}
+	for name := range exporters {
+		formats = append(formats, name)
+	}
+	sort.Strings(formats)
+	return formats
+}
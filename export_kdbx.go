@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+// kdbxKeyField is the field of KeePass entries that holds the pony key, so
+// they are imported with the same key again.
+const kdbxKeyField = "PonyKey"
+
+// exportKDBX writes the secrets to a KeePass database.
+func exportKDBX(cmd *exportCommand, keys []string) error {
+	if len(cmd.output) == 0 {
+		return errors.New("must pass the file to write the kdbx database to with --output")
+	}
+	if _, err := os.Stat(cmd.output); err == nil && !cmd.force {
+		return fmt.Errorf("%s already exists, use `--force` to overwrite it", cmd.output)
+	}
+
+	password, err := promptPassword(fmt.Sprintf("Master password for %s: ", cmd.output), true)
+	if err != nil {
+		return err
+	}
+	if len(password) == 0 {
+		return errors.New("master password is empty")
+	}
+
+	root := gokeepasslib.NewGroup()
+	root.Name = "pony"
+	for _, key := range keys {
+		sec := s.Secrets[key]
+		e := gokeepasslib.NewEntry()
+		e.Values = []gokeepasslib.ValueData{
+			{Key: "Title", Value: gokeepasslib.V{Content: key}},
+			{Key: "Password", Value: gokeepasslib.V{Content: sec.Value, Protected: w.NewBoolWrapper(true)}},
+			{Key: "Notes", Value: gokeepasslib.V{Content: sec.Note}},
+			{Key: kdbxKeyField, Value: gokeepasslib.V{Content: key}},
+		}
+		e.Tags = strings.Join(sec.Tags, ";")
+		root.Entries = append(root.Entries, e)
+	}
+
+	db := gokeepasslib.NewDatabase(gokeepasslib.WithDatabaseKDBXVersion4())
+	db.Content.Meta.DatabaseName = "pony"
+	db.Content.Root = &gokeepasslib.RootData{Groups: []gokeepasslib.Group{root}}
+	db.Credentials = gokeepasslib.NewPasswordCredentials(password)
+	if err := db.LockProtectedEntries(); err != nil {
+		return fmt.Errorf("locking the kdbx entries failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gokeepasslib.NewEncoder(&buf).Encode(db); err != nil {
+		return fmt.Errorf("encoding kdbx database failed: %v", err)
+	}
+	if err := writeOutput(cmd.output, buf.Bytes()); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d secret(s) to %s\n", len(keys), cmd.output)
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jessfraz/pony/gpg"
+)
+
+// exportPass writes the secrets to a pass password store, encrypted with gpg
+// to --to, the recipients of the secrets file, or the default gpg key.
+func exportPass(cmd *exportCommand, keys []string) error {
+	dir, err := passStoreDir(cmd.output)
+	if err != nil {
+		return err
+	}
+
+	recipients := cmd.to.values
+	if len(recipients) == 0 && s.backend == "gpg" {
+		recipients = s.Recipients
+	}
+	if len(recipients) == 0 {
+		recipients = keyids.values
+	}
+	if len(recipients) == 0 {
+		// Encrypt to the same key gpg encrypts the secrets file to by
+		// default.
+		r, err := gpgCipher{}.DefaultRecipients()
+		if err != nil {
+			return fmt.Errorf("must pass the gpg keyids to encrypt the pass store to with --to, finding the default key failed: %v", err)
+		}
+		recipients = r
+	}
+
+	// Check for conflicts before writing anything.
+	files := map[string]string{}
+	for _, key := range keys {
+		p, err := passPath(key)
+		if err != nil {
+			return err
+		}
+		files[key] = p + ".gpg"
+		if _, err := os.Stat(filepath.Join(dir, files[key])); err == nil && !cmd.force {
+			return fmt.Errorf("%s already exists, use `--force` to overwrite it", filepath.Join(dir, files[key]))
+		}
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dir, ".gpg-id")); err == nil && !equalLists(strings.Fields(string(b)), recipients) && !cmd.force {
+		return fmt.Errorf("%s is already encrypted to %s, use `--force` to overwrite it", dir, strings.Join(strings.Fields(string(b)), ", "))
+	}
+
+	// Build the new store in a temporary directory next to the store first,
+	// so a failure does not leave a half written store behind. An existing
+	// store is copied over so the files pony does not write are kept.
+	parent := filepath.Dir(filepath.Clean(dir))
+	if err := os.MkdirAll(parent, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(parent, "."+filepath.Base(dir)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	_, err = os.Stat(dir)
+	exists := err == nil
+	if exists {
+		if err := copyDir(dir, tmp); err != nil {
+			return fmt.Errorf("copying the pass store %s failed: %v", dir, err)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(tmp, ".gpg-id"), []byte(strings.Join(recipients, "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		sec := s.Secrets[key]
+
+		// pass keeps the password on the first line and anything else after.
+		content := sec.Value + "\n"
+		if len(sec.Note) > 0 {
+			content += sec.Note + "\n"
+		}
+		b, err := gpg.EncryptBytes([]byte(content), recipients...)
+		if err != nil {
+			return fmt.Errorf("encrypting %s failed: %v", key, err)
+		}
+
+		p := filepath.Join(tmp, files[key])
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(p, b, 0600); err != nil {
+			return err
+		}
+	}
+
+	// Move the new store into place with a single rename. An existing store
+	// is swapped with it and removed along with the temporary directory.
+	if exists {
+		if err := swapDirs(tmp, dir); err != nil {
+			return fmt.Errorf("replacing the pass store %s failed: %v", dir, err)
+		}
+	} else if err := os.Rename(tmp, dir); err != nil {
+		return fmt.Errorf("moving the pass store to %s failed: %v", dir, err)
+	}
+
+	fmt.Printf("Exported %d secret(s) to %s\n", len(keys), dir)
+	return nil
+}
+
+// copyDir copies the files, directories, and symlinks in src to the existing
+// directory dst, keeping their permissions.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		p := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			if rel == "." {
+				return os.Chmod(dst, info.Mode().Perm())
+			}
+			return os.Mkdir(p, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(target, p)
+		case info.Mode().IsRegular():
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(p, b, info.Mode().Perm())
+		}
+		return fmt.Errorf("%s is not a regular file", path)
+	})
+}
+
+// renameDirs replaces the directory b with a by moving b out of the way
+// first, for when swapDirs can not exchange them at once. a is left with the
+// contents of b.
+func renameDirs(a, b string) error {
+	old := a + ".old"
+	if err := os.Rename(b, old); err != nil {
+		return err
+	}
+	if err := os.Rename(a, b); err != nil {
+		os.Rename(old, b)
+		return err
+	}
+	return os.Rename(old, a)
+}
+
+// passPath returns the path in a pass store for the key. The first two
+// elements of the key are the domain, com.github.jessfraz is saved as
+// github.com/jessfraz.
+func passPath(key string) (string, error) {
+	parts := strings.Split(key, ".")
+	if len(parts) > 1 {
+		parts = append([]string{parts[1] + "." + parts[0]}, parts[2:]...)
+	}
+	for _, p := range parts {
+		if len(p) == 0 || p == ".." || strings.ContainsAny(p, `/\`) {
+			return "", fmt.Errorf("key %s can not be saved in a pass store", key)
+		}
+	}
+	return filepath.Join(parts...), nil
+}
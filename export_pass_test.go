@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/jessfraz/pony/gpg"
+)
+
+// setupGPGHome writes the keyrings of a new gpg key to a temporary GnuPG home
+// for the native gpg backend and returns the fingerprint of the key.
+func setupGPGHome(t *testing.T) string {
+	t.Helper()
+	oldHome, oldUse := gpg.Home, gpg.Use
+	t.Cleanup(func() {
+		gpg.Home, gpg.Use = oldHome, oldUse
+	})
+
+	e, err := openpgp.NewEntity("pony", "test", "pony@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gpg.Home = t.TempDir()
+	gpg.Use = gpg.Native
+
+	// SerializePrivate signs the identities, so it has to come first.
+	sec, err := os.Create(filepath.Join(gpg.Home, "secring.gpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sec.Close()
+	if err := e.SerializePrivate(sec, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := os.Create(filepath.Join(gpg.Home, "pubring.gpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+	if err := e.Serialize(pub); err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("%X", e.PrimaryKey.Fingerprint)
+}
+
+// setupPassExport sets the secrets to export and restores the globals after
+// the test.
+func setupPassExport(t *testing.T, secrets map[string]secret) {
+	t.Helper()
+	oldSecrets, oldKeyids := s, keyids
+	t.Cleanup(func() {
+		s, keyids = oldSecrets, oldKeyids
+	})
+	s = secretFile{Secrets: secrets, backend: "gpg"}
+	keyids = listFlag{}
+}
+
+func TestExportPassDefaultKey(t *testing.T) {
+	fingerprint := setupGPGHome(t)
+	setupPassExport(t, map[string]secret{
+		"com.github.jessfraz.token": {Value: "s3cret", Note: "for hub"},
+	})
+
+	dir := filepath.Join(t.TempDir(), "store")
+	if err := exportPass(&exportCommand{output: dir}, []string{"com.github.jessfraz.token"}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, ".gpg-id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(b)); got != fingerprint {
+		t.Fatalf("expected the store to be encrypted to %s, got %s", fingerprint, got)
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(dir, "github.com", "jessfraz", "token.gpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = gpg.DecryptBytes(b)
+	if err != nil {
+		t.Fatalf("decrypting the exported secret failed: %v", err)
+	}
+	if string(b) != "s3cret\nfor hub\n" {
+		t.Fatalf("expected the value and note, got %q", b)
+	}
+}
+
+func TestExportPassReplacesStore(t *testing.T) {
+	fingerprint := setupGPGHome(t)
+	setupPassExport(t, map[string]secret{
+		"com.github.jessfraz.token": {Value: "new"},
+	})
+
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "store")
+	for name, content := range map[string]string{
+		".gpg-id":                       fingerprint + "\n",
+		".git/config":                   "[core]\n",
+		"github.com/jessfraz/token.gpg": "old",
+		"example.com/other.gpg":         "other",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := &exportCommand{output: dir}
+	if err := exportPass(cmd, []string{"com.github.jessfraz.token"}); err == nil {
+		t.Fatal("expected the export to refuse to overwrite an existing secret")
+	}
+	cmd.force = true
+	if err := exportPass(cmd, []string{"com.github.jessfraz.token"}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	// The files pony does not know about are kept.
+	for name, content := range map[string]string{
+		".git/config":           "[core]\n",
+		"example.com/other.gpg": "other",
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(b) != content {
+			t.Errorf("expected %s to be kept, got %q: %v", name, b, err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "github.com", "jessfraz", "token.gpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = gpg.DecryptBytes(b)
+	if err != nil {
+		t.Fatalf("decrypting the exported secret failed: %v", err)
+	}
+	if string(b) != "new\n" {
+		t.Fatalf("expected the new value, got %q", b)
+	}
+
+	// Nothing is left behind next to the store.
+	files, err := ioutil.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		names := []string{}
+		for _, f := range files {
+			names = append(names, f.Name())
+		}
+		t.Fatalf("expected only the store in %s, got %s", parent, strings.Join(names, ", "))
+	}
+}
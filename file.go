@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes the data to a temporary file in the same directory
+// as filename, syncs it to disk, and renames it over filename. This way the
+// file is never left truncated if something fails partway through. The mode
+// of an existing file is preserved and the previous contents are kept in
+// rolling backups.
+func writeFileAtomic(filename string, data []byte, backups int) (err error) {
+	mode := os.FileMode(0600)
+	fi, err := os.Stat(filename)
+	exists := err == nil
+	if exists {
+		mode = fi.Mode()
+	}
+
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Chmod(mode); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	if exists {
+		if err = rotateBackups(filename, backups); err != nil {
+			return fmt.Errorf("backing up %s failed: %v", filename, err)
+		}
+	}
+
+	if err = os.Rename(f.Name(), filename); err != nil {
+		return err
+	}
+
+	// Sync the directory so the rename is on disk as well. Not all
+	// platforms support this so ignore the errors.
+	if d, derr := os.Open(dir); derr == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
+
+// rotateBackups shifts the backups of filename up by one, dropping the
+// oldest, and copies filename to the first backup.
+func rotateBackups(filename string, backups int) error {
+	if backups < 1 {
+		return nil
+	}
+
+	for i := backups - 1; i > 0; i-- {
+		if err := os.Rename(backupName(filename, i), backupName(filename, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return copyFile(filename, backupName(filename, 1))
+}
+
+// backupName returns the name of the nth backup for filename.
+func backupName(filename string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", filename, n)
+}
+
+// copyFile copies the file at src to dst keeping the file mode.
+func copyFile(src, dst string) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(dst, b, fi.Mode())
+}
+
+// shredDir overwrites every file in the directory with zeros before
+// removing the directory, so the plaintext does not linger on disk.
+func shredDir(dir string) error {
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+		return shredFile(path, fi.Size())
+	})
+	if rerr := os.RemoveAll(dir); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// shredFile overwrites the file with zeros and syncs it to disk.
+func shredFile(path string, size int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(make([]byte, size)); err != nil {
+		f.Close()
+		return fmt.Errorf("overwriting %s failed: %v", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeOutput writes the data to the file, or stdout if filename is empty.
+// The data holds secrets so only we can read the file, even if it already
+// existed.
+func writeOutput(filename string, data []byte) error {
+	if len(filename) == 0 {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := f.Chmod(0600); err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("writing to %s failed: %v", filename, err)
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// secretRecord is how a secret is shown in the structured output formats.
+type secretRecord struct {
+	Key       string   `json:"key" yaml:"key"`
+	Value     string   `json:"value,omitempty" yaml:"value,omitempty"`
+	Note      string   `json:"note,omitempty" yaml:"note,omitempty"`
+	Tags      []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Version   int      `json:"version,omitempty" yaml:"version,omitempty"`
+	CreatedAt string   `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	UpdatedAt string   `json:"updated_at,omitempty" yaml:"updated_at,omitempty"`
+}
+
+func newSecretRecord(key string, sec secret, value string) secretRecord {
+	return secretRecord{
+		Key:       key,
+		Value:     value,
+		Note:      sec.Note,
+		Tags:      sec.Tags,
+		Version:   sec.Version,
+		CreatedAt: formatRecordTime(sec.CreatedAt),
+		UpdatedAt: formatRecordTime(sec.UpdatedAt),
+	}
+}
+
+func formatRecordTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// writeRecords writes the records to w in the given format which is one of
+// json, yaml, dotenv, csv, or a Go template. If single is true, a single
+// object is written for the structured formats instead of a list.
+func writeRecords(w io.Writer, format string, records []secretRecord, single bool) error {
+	var v interface{} = records
+	if single && len(records) == 1 {
+		v = records[0]
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case "dotenv":
+		for _, r := range records {
+			if _, err := fmt.Fprintf(w, "%s=%s\n", envName(r.Key, ""), dotenvQuote(r.Value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"key", "value", "note", "tags", "created_at", "updated_at"}); err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := cw.Write([]string{r.Key, r.Value, r.Note, strings.Join(r.Tags, ","), r.CreatedAt, r.UpdatedAt}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	// Otherwise the format is a Go template which is executed for every
+	// record.
+	if !strings.Contains(format, "{{") {
+		return fmt.Errorf("unknown format %q, must be one of json, yaml, dotenv, csv, or a Go template", format)
+	}
+	tmpl, err := template.New("format").Parse(format)
+	if err != nil {
+		return fmt.Errorf("parsing format template failed: %v", err)
+	}
+	for _, r := range records {
+		if err := tmpl.Execute(w, r); err != nil {
+			return fmt.Errorf("executing format template failed: %v", err)
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/jessfraz/pony/password"
+)
+
+const generateHelp = `Generate a random password or passphrase and save it as a secret.`
+
+func (cmd *generateCommand) Name() string      { return "generate" }
+func (cmd *generateCommand) Args() string      { return "[OPTIONS] KEY" }
+func (cmd *generateCommand) ShortHelp() string { return generateHelp }
+func (cmd *generateCommand) LongHelp() string  { return generateHelp }
+func (cmd *generateCommand) Hidden() bool      { return false }
+
+func (cmd *generateCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.force, "force", false, "force overwrite existing value")
+	fs.BoolVar(&cmd.force, "f", false, "force overwrite existing value")
+	fs.StringVar(&cmd.note, "note", "", "note about what the secret is for")
+	fs.Var(&cmd.tags, "tag", "tags for the secret, comma separated or repeated")
+	cmd.generator.register(fs)
+}
+
+type generateCommand struct {
+	force bool
+	note  string
+	tags  listFlag
+
+	generator generator
+}
+
+func (cmd *generateCommand) Run(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return errors.New("must pass a key")
+	}
+
+	key := args[0]
+	value, err := cmd.generator.generate()
+	if err != nil {
+		return err
+	}
+
+	if err := s.setKeyValue(key, value, cmd.force, cmd.note, cmd.tags.values); err != nil {
+		return err
+	}
+
+	cmd.generator.print(key, value)
+	return nil
+}
+
+// generator holds the options for generating a secret value, shared by the
+// generate and create commands.
+type generator struct {
+	length      int
+	noLower     bool
+	noUpper     bool
+	noDigits    bool
+	noSymbols   bool
+	noAmbiguous bool
+
+	words     int
+	separator string
+
+	show bool
+}
+
+func (g *generator) register(fs *flag.FlagSet) {
+	fs.IntVar(&g.length, "length", 24, "length of the generated password")
+	fs.BoolVar(&g.noLower, "no-lower", false, "do not use lowercase letters in the generated password")
+	fs.BoolVar(&g.noUpper, "no-upper", false, "do not use uppercase letters in the generated password")
+	fs.BoolVar(&g.noDigits, "no-digits", false, "do not use digits in the generated password")
+	fs.BoolVar(&g.noSymbols, "no-symbols", false, "do not use symbols in the generated password")
+	fs.BoolVar(&g.noAmbiguous, "no-ambiguous", false, "do not use characters that are easily confused like 0 and O")
+	fs.IntVar(&g.words, "words", 0, "generate a diceware passphrase with this many words instead of a password")
+	fs.StringVar(&g.separator, "separator", "-", "separator between the words of a passphrase")
+	fs.BoolVar(&g.show, "show", false, "print the generated value")
+}
+
+func (g *generator) generate() (string, error) {
+	if g.words > 0 {
+		return password.Passphrase(g.words, g.separator)
+	}
+
+	return password.Generate(password.Options{
+		Length:           g.length,
+		Lower:            !g.noLower,
+		Upper:            !g.noUpper,
+		Digits:           !g.noDigits,
+		Symbols:          !g.noSymbols,
+		ExcludeAmbiguous: g.noAmbiguous,
+	})
+}
+
+// print lets the user know the value was generated, without echoing it
+// unless they asked for it.
+func (g *generator) print(key, value string) {
+	if g.show {
+		fmt.Printf("Generated %s %s\n", key, value)
+		return
+	}
+	fmt.Printf("Generated %s\n", key)
+}
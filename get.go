@@ -5,6 +5,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"os"
 
 	"github.com/atotto/clipboard"
 )
@@ -19,10 +20,12 @@ func (cmd *getCommand) Hidden() bool      { return false }
 
 func (cmd *getCommand) Register(fs *flag.FlagSet) {
 	fs.BoolVar(&cmd.copy, "copy", false, "copy the value to clipboard")
+	fs.StringVar(&cmd.format, "format", "", "output format: json, yaml, dotenv, csv, or a Go template like '{{.Value}}'")
 }
 
 type getCommand struct {
-	copy bool
+	copy   bool
+	format string
 }
 
 func (cmd *getCommand) Run(ctx context.Context, args []string) error {
@@ -32,11 +35,18 @@ func (cmd *getCommand) Run(ctx context.Context, args []string) error {
 
 	// Get the key value pair from secrets.
 	key := args[0]
-	if _, ok := s.Secrets[key]; !ok {
-		return fmt.Errorf("secret for key %s does not exist", key)
+	sec, err := getSecret(key)
+	if err != nil {
+		return err
 	}
 
-	fmt.Println(s.Secrets[key])
+	if len(cmd.format) > 0 {
+		if err := writeRecords(os.Stdout, cmd.format, []secretRecord{newSecretRecord(key, sec, sec.Value)}, true); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println(sec.Value)
+	}
 
 	if !cmd.copy {
 		// Return early.
@@ -44,10 +54,23 @@ func (cmd *getCommand) Run(ctx context.Context, args []string) error {
 	}
 
 	// Copy to clipboard.
-	if err := clipboard.WriteAll(s.Secrets[key]); err != nil {
+	if err := clipboard.WriteAll(sec.Value); err != nil {
 		return fmt.Errorf("clipboard copy failed: %v", err)
 	}
 	fmt.Println("Copied to clipboard!")
 
 	return nil
 }
+
+// getSecret returns the secret for the key from the agent or the secrets
+// file.
+func getSecret(key string) (secret, error) {
+	if agent != nil {
+		return agent.get(key)
+	}
+	sec, ok := s.Secrets[key]
+	if !ok {
+		return sec, fmt.Errorf("secret for key %s does not exist", key)
+	}
+	return sec, nil
+}
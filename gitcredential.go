@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const gitCredentialHelp = `Git credential helper backed by pony.`
+
+const gitCredentialLongHelp = gitCredentialHelp + `
+
+Speaks the git credential helper protocol on stdin and stdout. The key of a
+credential is made with --key-template from the protocol, host, path, and
+username git asks for. The reverse function turns a host like github.com into
+com.github. When git does not know the username yet the first key that
+matches the template with any username is used. A credential is only erased
+if git passes no password or the one that is stored.
+
+Symlink pony as git-credential-pony somewhere on your PATH and run:
+
+  git config --global credential.helper pony
+
+or without the symlink:
+
+  git config --global credential.helper '!pony git-credential'`
+
+func (cmd *gitCredentialCommand) Name() string      { return "git-credential" }
+func (cmd *gitCredentialCommand) Args() string      { return "[OPTIONS] get|store|erase" }
+func (cmd *gitCredentialCommand) ShortHelp() string { return gitCredentialHelp }
+func (cmd *gitCredentialCommand) LongHelp() string  { return gitCredentialLongHelp }
+func (cmd *gitCredentialCommand) Hidden() bool      { return false }
+
+func (cmd *gitCredentialCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.keyTemplate, "key-template", getEnvDefault("PONY_GIT_CREDENTIAL_TEMPLATE", defaultGitCredentialTemplate), "Go template for the key of a credential, with .Protocol, .Host, .Path, and .Username (or env var PONY_GIT_CREDENTIAL_TEMPLATE)")
+}
+
+type gitCredentialCommand struct {
+	keyTemplate string
+}
+
+// defaultGitCredentialTemplate saves the token for jessfraz on github.com as
+// com.github.jessfraz.token.
+const defaultGitCredentialTemplate = "{{reverse .Host}}.{{.Username}}.token"
+
+// gitCredential is a credential of the git credential helper protocol.
+type gitCredential struct {
+	Protocol string
+	Host     string
+	Path     string
+	Username string
+	Password string
+}
+
+func (cmd *gitCredentialCommand) Run(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return errors.New("must pass an operation: get, store, or erase")
+	}
+
+	tmpl, err := template.New("key").Funcs(template.FuncMap{
+		"reverse": reverseDomain,
+	}).Option("missingkey=error").Parse(cmd.keyTemplate)
+	if err != nil {
+		return fmt.Errorf("parsing key template failed: %v", err)
+	}
+
+	c, err := readGitCredential(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "get":
+		key, err := cmd.findKey(tmpl, c)
+		if err != nil || len(key) == 0 {
+			// Let git ask the next helper or the user.
+			return err
+		}
+		fmt.Printf("username=%s\n", c.Username)
+		fmt.Printf("password=%s\n", s.Secrets[key].Value)
+		return nil
+	case "store":
+		if len(c.Username) == 0 || len(c.Password) == 0 {
+			return nil
+		}
+		key, err := executeKeyTemplate(tmpl, c)
+		if err != nil {
+			return err
+		}
+		if sec, ok := s.Secrets[key]; ok && sec.Value == c.Password {
+			return nil
+		}
+		return s.setKeyValue(key, c.Password, true, "", nil)
+	case "erase":
+		if len(c.Username) == 0 {
+			return nil
+		}
+		key, err := executeKeyTemplate(tmpl, c)
+		if err != nil {
+			return err
+		}
+		// Git asks every helper to erase a credential that was rejected, only
+		// erase ours if it is the one that was rejected.
+		sec, ok := s.Secrets[key]
+		if !ok || (len(c.Password) > 0 && c.Password != sec.Value) {
+			return nil
+		}
+		delete(s.Secrets, key)
+		return writeSecretsFile(file, s)
+	}
+
+	// Unknown operations have to be ignored so newer versions of git keep
+	// working.
+	return nil
+}
+
+// findKey returns the key for the credential. If git does not know the
+// username yet it is taken from the first key matching the template.
+func (cmd *gitCredentialCommand) findKey(tmpl *template.Template, c *gitCredential) (string, error) {
+	if len(c.Username) > 0 {
+		key, err := executeKeyTemplate(tmpl, c)
+		if _, ok := s.Secrets[key]; !ok {
+			return "", err
+		}
+		return key, err
+	}
+
+	// Render the template with a marker for the username to find out what
+	// comes before and after it.
+	const marker = "\x00"
+	m := *c
+	m.Username = marker
+	pattern, err := executeKeyTemplate(tmpl, &m)
+	if err != nil {
+		return "", err
+	}
+	i := strings.Index(pattern, marker)
+	if i < 0 {
+		// The template does not use the username.
+		if _, ok := s.Secrets[pattern]; ok {
+			return pattern, nil
+		}
+		return "", nil
+	}
+	prefix, suffix := pattern[:i], pattern[i+len(marker):]
+
+	keys := []string{}
+	for key := range s.Secrets {
+		if len(key) > len(prefix)+len(suffix) && strings.HasPrefix(key, prefix) && strings.HasSuffix(key, suffix) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return "", nil
+	}
+	sort.Strings(keys)
+	c.Username = strings.TrimSuffix(strings.TrimPrefix(keys[0], prefix), suffix)
+	return keys[0], nil
+}
+
+// executeKeyTemplate returns the key for the credential.
+func executeKeyTemplate(tmpl *template.Template, c *gitCredential) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, c); err != nil {
+		return "", fmt.Errorf("executing key template failed: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// readGitCredential reads the attributes git passes to credential helpers,
+// one key=value per line up to an empty line.
+func readGitCredential(r io.Reader) (*gitCredential, error) {
+	c := &gitCredential{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(line) == 0 {
+			break
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid credential attribute %q", line)
+		}
+		key, value := line[:i], line[i+1:]
+		switch key {
+		case "protocol":
+			c.Protocol = value
+		case "host":
+			c.Host = value
+		case "path":
+			c.Path = value
+		case "username":
+			c.Username = value
+		case "password":
+			c.Password = value
+		case "url":
+			u, err := url.Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("parsing credential url failed: %v", err)
+			}
+			c.Protocol, c.Host, c.Path = u.Scheme, u.Host, strings.TrimPrefix(u.Path, "/")
+			if u.User != nil {
+				c.Username = u.User.Username()
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading credential failed: %v", err)
+	}
+	return c, nil
+}
+
+// reverseDomain turns a host like github.com into com.github. The port is
+// left out.
+func reverseDomain(host string) string {
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+	labels := strings.Split(host, ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return strings.Join(labels, ".")
+}
@@ -1,16 +1,27 @@
 module github.com/jessfraz/pony
 
-go 1.14
+go 1.19
 
 require (
+	filippo.io/age v1.1.1
+	github.com/ProtonMail/go-crypto v1.1.6
 	github.com/atotto/clipboard v0.1.0
-	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/genuinetools/pkg v0.0.0-20180717201740-54e648406b2d
-	github.com/onsi/gomega v1.4.2 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/sirupsen/logrus v1.0.5
+	github.com/tobischo/gokeepasslib/v3 v3.2.0
+	golang.org/x/crypto v0.17.0
+	golang.org/x/sys v0.16.0
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/aead/argon2 v0.0.0-20180111183520-a87724528b07 // indirect
+	github.com/aead/chacha20 v0.0.0-20180709150244-8b13a72661da // indirect
+	github.com/cloudflare/circl v1.3.7 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/testify v1.2.2 // indirect
-	golang.org/x/crypto v0.0.0-20180718160520-a2144134853f // indirect
+	golang.org/x/term v0.15.0 // indirect
 	gopkg.in/airbrake/gobrake.v2 v2.0.9 // indirect
 	gopkg.in/gemnasium/logrus-airbrake-hook.v2 v2.1.2 // indirect
 )
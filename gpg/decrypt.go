@@ -8,6 +8,9 @@ import (
 	"io/ioutil"
 	"os/exec"
 	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/errors"
 )
 
 // Decrypt a file.
@@ -24,13 +27,69 @@ func Decrypt(f string) (io.Reader, error) {
 		return nil, err
 	}
 
-	// Run the command.
+	out, err = DecryptBytes(out)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(out), nil
+}
+
+// DecryptBytes decrypts a binary OpenPGP message.
+func DecryptBytes(b []byte) ([]byte, error) {
+	switch Use {
+	case Exec:
+		return decryptExec(b)
+	default:
+		out, err := decryptNative(b)
+		if fallbackToExec(err) {
+			return decryptExec(b)
+		}
+		return out, err
+	}
+}
+
+func decryptExec(b []byte) ([]byte, error) {
+	var stderr bytes.Buffer
 	args := []string{"--decrypt"}
 	cmd := exec.Command("gpg", args...)
-	cmd.Stdin = bytes.NewBuffer(out)
-	out, err = cmd.Output()
+	cmd.Stdin = bytes.NewBuffer(b)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("gpg [gpg %s] failed with stdout %q, error: %v", strings.Join(args, " "), string(out), err)
+		return nil, fmt.Errorf("gpg [gpg %s] failed with stderr %q, error: %v", strings.Join(args, " "), stderr.String(), err)
 	}
-	return bytes.NewReader(out), nil
+	return out, nil
+}
+
+// secretKeys caches the secret keyring of secretKeysHome, so the passphrase
+// is only asked for once when decrypting many messages.
+var (
+	secretKeys     openpgp.EntityList
+	secretKeysHome string
+)
+
+func decryptNative(b []byte) ([]byte, error) {
+	if secretKeys == nil || secretKeysHome != Home {
+		secring, err := readKeyRing(secretKeyring)
+		if err != nil {
+			return nil, err
+		}
+		secretKeys, secretKeysHome = secring, Home
+	}
+
+	md, err := openpgp.ReadMessage(bytes.NewReader(b), secretKeys, promptPassphrase, nil)
+	if err != nil {
+		if _, ok := err.(errors.UnsupportedError); ok || err == errors.ErrKeyIncorrect {
+			// The message is for a key that is not in the keyring or that
+			// we do not support.
+			return nil, unsupportedError{fmt.Errorf("openpgp decrypt failed: %v", err)}
+		}
+		return nil, fmt.Errorf("openpgp decrypt failed: %v", err)
+	}
+
+	out, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, fmt.Errorf("openpgp decrypt failed: %v", err)
+	}
+	return out, nil
 }
@@ -0,0 +1,55 @@
+package gpg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// DefaultKey returns the fingerprint of the key Encrypt encrypts to when no
+// keyids are given, the first key in the secret keyring.
+func DefaultKey() (string, error) {
+	switch Use {
+	case Exec:
+		return defaultKeyExec()
+	default:
+		key, err := defaultKeyNative()
+		if fallbackToExec(err) {
+			return defaultKeyExec()
+		}
+		return key, err
+	}
+}
+
+func defaultKeyExec() (string, error) {
+	var stderr bytes.Buffer
+	args := []string{"--list-secret-keys", "--with-colons"}
+	cmd := exec.Command("gpg", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("gpg [gpg %s] failed with stderr %q, error: %v", strings.Join(args, " "), stderr.String(), err)
+	}
+
+	// The first fpr record is the fingerprint of the first primary key.
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, ":")
+		if fields[0] == "fpr" && len(fields) > 9 && len(fields[9]) > 0 {
+			return fields[9], nil
+		}
+	}
+	return "", errors.New("no secret keys found by gpg")
+}
+
+func defaultKeyNative() (string, error) {
+	secring, err := readKeyRing(secretKeyring)
+	if err != nil {
+		return "", err
+	}
+	if len(secring) == 0 {
+		return "", unsupportedError{errors.New("the secret keyring is empty")}
+	}
+	return fmt.Sprintf("%X", secring[0].PrimaryKey.Fingerprint), nil
+}
@@ -6,23 +6,76 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/errors"
 )
 
-// Encrypt a byte with the given publicKeyring.
-func Encrypt(b []byte, keyid string) ([]byte, error) {
-	buf := bytes.NewBuffer(b)
-	var stdout bytes.Buffer
+// Encrypt a byte with the given publicKeyrings.
+func Encrypt(b []byte, keyids ...string) ([]byte, error) {
+	out, err := EncryptBytes(b, keyids...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Base64 encode.
+	return []byte(base64.StdEncoding.EncodeToString(out)), nil
+}
+
+// EncryptBytes encrypts a byte with the given publicKeyrings and returns the
+// binary OpenPGP message.
+func EncryptBytes(b []byte, keyids ...string) ([]byte, error) {
+	switch Use {
+	case Exec:
+		return encryptExec(b, keyids)
+	default:
+		out, err := encryptNative(b, keyids)
+		if fallbackToExec(err) {
+			return encryptExec(b, keyids)
+		}
+		return out, err
+	}
+}
+
+func encryptExec(b []byte, keyids []string) ([]byte, error) {
+	var stderr bytes.Buffer
 	args := []string{"--encrypt"}
-	if len(keyid) > 0 {
-		args = append(args, "--recipient", keyid)
+	for _, keyid := range keyids {
+		if len(keyid) > 0 {
+			args = append(args, "--recipient", keyid)
+		}
 	}
 	cmd := exec.Command("gpg", args...)
-	cmd.Stdin = buf
+	cmd.Stdin = bytes.NewBuffer(b)
+	cmd.Stderr = &stderr
 	out, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("gpg [gpg %s] failed with stdout %q, error: %v", strings.Join(args, " "), stdout.String(), err)
+		return nil, fmt.Errorf("gpg [gpg %s] failed with stderr %q, error: %v", strings.Join(args, " "), stderr.String(), err)
 	}
+	return out, nil
+}
 
-	// Base64 encode.
-	return []byte(base64.StdEncoding.EncodeToString(out)), nil
+func encryptNative(b []byte, keyids []string) ([]byte, error) {
+	to, err := findRecipients(keyids)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w, err := openpgp.Encrypt(&buf, to, nil, nil, nil)
+	if err != nil {
+		if _, ok := err.(errors.InvalidArgumentError); ok {
+			// None of the subkeys of the recipients are supported.
+			return nil, unsupportedError{fmt.Errorf("openpgp encrypt failed: %v", err)}
+		}
+		return nil, fmt.Errorf("openpgp encrypt failed: %v", err)
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, fmt.Errorf("openpgp encrypt failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("openpgp encrypt failed: %v", err)
+	}
+
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,61 @@
+// Package gpg encrypts and decrypts data with OpenPGP, either natively in Go
+// or by shelling out to the gpg binary.
+package gpg
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// Backend defines which implementation Encrypt and Decrypt use.
+type Backend string
+
+const (
+	// Native uses the pure Go OpenPGP implementation with the keyrings found
+	// in Home. It falls back to Exec for keyrings or keys it can not handle.
+	Native Backend = "native"
+	// Exec shells out to the gpg binary on the PATH.
+	Exec Backend = "exec"
+)
+
+var (
+	// Use is the backend used by Encrypt and Decrypt.
+	Use = Native
+
+	// Home is the GnuPG home directory the native backend reads the
+	// public and secret keyrings from.
+	Home string
+)
+
+// ParseBackend returns the Backend for the given name.
+func ParseBackend(name string) (Backend, error) {
+	switch b := Backend(name); b {
+	case Native, Exec:
+		return b, nil
+	}
+	return "", fmt.Errorf("unknown gpg backend %q, must be one of %s or %s", name, Native, Exec)
+}
+
+// unsupportedError is a failure of the native backend that the gpg binary
+// can deal with, like keyrings GnuPG 2.1 and later no longer write or keys
+// the Go OpenPGP implementation does not support.
+type unsupportedError struct {
+	err error
+}
+
+func (e unsupportedError) Error() string {
+	return e.err.Error()
+}
+
+// fallbackToExec checks if the native backend failed in a way the gpg binary
+// can handle. If it is installed the exec backend is used from now on.
+func fallbackToExec(err error) bool {
+	if _, ok := err.(unsupportedError); !ok {
+		return false
+	}
+	if _, err := exec.LookPath("gpg"); err != nil {
+		return false
+	}
+	Use = Exec
+	return true
+}
@@ -0,0 +1,142 @@
+package gpg
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// setupHome writes the keyrings of a new key to a temporary Home for the
+// native backend and returns the directory.
+func setupHome(t *testing.T) string {
+	t.Helper()
+	oldHome, oldUse, oldKeys := Home, Use, secretKeys
+	t.Cleanup(func() {
+		Home, Use, secretKeys = oldHome, oldUse, oldKeys
+	})
+
+	e, err := openpgp.NewEntity("pony", "test", "pony@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Home = t.TempDir()
+	Use = Native
+
+	// SerializePrivate signs the identities, so it has to come first.
+	var sec, pub bytes.Buffer
+	if err := e.SerializePrivate(&sec, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(&pub); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(Home, secretKeyring), sec.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(Home, publicKeyring), pub.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return Home
+}
+
+func TestNativeRoundTrip(t *testing.T) {
+	setupHome(t)
+
+	plaintext := []byte("s3cret\n")
+	for _, keyids := range [][]string{{"pony@example.com"}, nil} {
+		b, err := EncryptBytes(plaintext, keyids...)
+		if err != nil {
+			t.Fatalf("encrypting to %v failed: %v", keyids, err)
+		}
+		out, err := DecryptBytes(b)
+		if err != nil {
+			t.Fatalf("decrypting failed: %v", err)
+		}
+		if !bytes.Equal(out, plaintext) {
+			t.Fatalf("expected %q, got %q", plaintext, out)
+		}
+	}
+	if Use != Native {
+		t.Fatalf("expected the native backend to be used, fell back to %s", Use)
+	}
+}
+
+func TestNativeEncryptExecDecrypt(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+	home := setupHome(t)
+
+	// Import the key into a GnuPG home of its own for the gpg binary.
+	gnupgHome := t.TempDir()
+	t.Setenv("GNUPGHOME", gnupgHome)
+	t.Cleanup(func() {
+		exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+	})
+	if out, err := exec.Command("gpg", "--batch", "--import", filepath.Join(home, secretKeyring)).CombinedOutput(); err != nil {
+		t.Fatalf("importing the key failed: %v\n%s", err, out)
+	}
+
+	plaintext := []byte("s3cret\n")
+	b, err := EncryptBytes(plaintext, "pony@example.com")
+	if err != nil {
+		t.Fatalf("encrypting failed: %v", err)
+	}
+	out, err := decryptExec(b)
+	if err != nil {
+		t.Fatalf("decrypting with gpg failed: %v", err)
+	}
+	if !bytes.Equal(out, plaintext) {
+		t.Fatalf("expected %q, got %q", plaintext, out)
+	}
+}
+
+func TestFallbackToExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake gpg is a shell script")
+	}
+	setupHome(t)
+	// GnuPG 2.1 and later do not write a secret keyring.
+	if err := os.Remove(filepath.Join(Home, secretKeyring)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fake gpg that always decrypts to the same thing.
+	bin := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(bin, "gpg"), []byte("#!/bin/sh\nprintf decrypted\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	out, err := DecryptBytes([]byte("ciphertext"))
+	if err != nil {
+		t.Fatalf("expected the fallback to the gpg binary, got %v", err)
+	}
+	if string(out) != "decrypted" {
+		t.Fatalf("expected the output of the gpg binary, got %q", out)
+	}
+	if Use != Exec {
+		t.Fatalf("expected the exec backend to be used from now on, got %s", Use)
+	}
+
+	// Other errors are not hidden by the fallback.
+	Use = Native
+	if fallbackToExec(os.ErrPermission) {
+		t.Fatal("expected no fallback for errors the gpg binary can not help with")
+	}
+
+	// Without the gpg binary the error of the native backend is kept.
+	t.Setenv("PATH", t.TempDir())
+	if _, err := DecryptBytes([]byte("ciphertext")); err == nil {
+		t.Fatal("expected an error without a secret keyring or the gpg binary")
+	}
+	if Use != Native {
+		t.Fatalf("expected to stay on the native backend, got %s", Use)
+	}
+}
@@ -0,0 +1,206 @@
+package gpg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	publicKeyring = "pubring.gpg"
+	secretKeyring = "secring.gpg"
+)
+
+// exportHint tells how to make keyrings the native backend can read. They
+// go in a directory of their own, GnuPG 2.1 and later would try to migrate a
+// secring.gpg in their home through gpg-agent again.
+const exportHint = "export your keys to a separate directory with `mkdir -m 700 DIR && gpg --export > DIR/" + publicKeyring + " && gpg --export-secret-keys > DIR/" + secretKeyring + "` and pass it with --gpgpath, or use the exec gpg backend"
+
+// readKeyRing reads the keyring with the given name from the GnuPG home
+// directory. Both binary and ASCII armored keyrings are supported.
+func readKeyRing(name string) (openpgp.EntityList, error) {
+	p := filepath.Join(Home, name)
+	b, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		// GnuPG 2.1 and later keep the keys in a keybox instead.
+		return nil, unsupportedError{fmt.Errorf("reading keyring failed: %v (%s)", err, exportHint)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading keyring failed: %v (%s)", err, exportHint)
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN")) {
+		el, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+		if err != nil {
+			return nil, keyRingError(p, err)
+		}
+		return el, nil
+	}
+
+	el, err := openpgp.ReadKeyRing(bytes.NewReader(b))
+	if err != nil {
+		return nil, keyRingError(p, err)
+	}
+	return el, nil
+}
+
+// keyRingError returns the error for reading the keyring, which only has keys
+// we do not support if ReadKeyRing skipped them all.
+func keyRingError(p string, err error) error {
+	switch err.(type) {
+	case pgperrors.UnsupportedError, pgperrors.StructuralError:
+		return unsupportedError{fmt.Errorf("reading keyring %s failed: %v", p, err)}
+	}
+	return fmt.Errorf("reading keyring %s failed: %v", p, err)
+}
+
+// findRecipients returns the entities to encrypt to for the given keyids.
+// A keyid can be a short or long key id, a fingerprint, or part of a user id
+// like an email address. If no keyids are given, the first key in the secret
+// keyring is used.
+func findRecipients(keyids []string) (openpgp.EntityList, error) {
+	to := openpgp.EntityList{}
+	for _, keyid := range keyids {
+		if len(keyid) == 0 {
+			continue
+		}
+		e, err := findRecipient(keyid)
+		if err != nil {
+			return nil, err
+		}
+		to = append(to, e)
+	}
+	if len(to) > 0 {
+		return to, nil
+	}
+
+	secring, err := readKeyRing(secretKeyring)
+	if err != nil {
+		return nil, err
+	}
+	if len(secring) == 0 {
+		return nil, unsupportedError{errors.New("no keyid given and the secret keyring is empty")}
+	}
+	return secring[:1], nil
+}
+
+// findRecipient returns the entity to encrypt to for the given keyid.
+func findRecipient(keyid string) (*openpgp.Entity, error) {
+	// The secret keyring also holds public keys so use both, but only fail
+	// if neither of them could be read.
+	keys, err := readKeyRing(publicKeyring)
+	secring, serr := readKeyRing(secretKeyring)
+	if err != nil && serr != nil {
+		return nil, err
+	}
+	keys = append(keys, secring...)
+
+	for _, e := range keys {
+		if matchEntity(e, keyid) {
+			return e, nil
+		}
+	}
+
+	// The key could be one ReadKeyRing skipped because it is not supported.
+	return nil, unsupportedError{fmt.Errorf("no public key found for %q in %s", keyid, Home)}
+}
+
+// matchEntity checks if the entity matches the keyid the same way gpg would
+// for the common cases.
+func matchEntity(e *openpgp.Entity, keyid string) bool {
+	hex := strings.ToUpper(strings.TrimPrefix(strings.Replace(keyid, " ", "", -1), "0x"))
+	if isHex(hex) && (len(hex) == 8 || len(hex) == 16 || len(hex) == 40) {
+		fingerprints := []string{fmt.Sprintf("%X", e.PrimaryKey.Fingerprint)}
+		for _, sub := range e.Subkeys {
+			fingerprints = append(fingerprints, fmt.Sprintf("%X", sub.PublicKey.Fingerprint))
+		}
+		for _, fp := range fingerprints {
+			if strings.HasSuffix(fp, hex) {
+				return true
+			}
+		}
+	}
+
+	for name := range e.Identities {
+		if strings.Contains(strings.ToLower(name), strings.ToLower(keyid)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9') && !(c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// promptPassphrase is an openpgp.PromptFunction that asks the user for the
+// passphrase of the first encrypted private key.
+func promptPassphrase(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+	if symmetric {
+		return nil, errors.New("symmetrically encrypted files are not supported")
+	}
+
+	for _, k := range keys {
+		if k.PrivateKey == nil || !k.PrivateKey.Encrypted {
+			continue
+		}
+
+		name := fmt.Sprintf("%X", k.PublicKey.KeyId)
+		for n := range k.Entity.Identities {
+			name = n
+			break
+		}
+
+		passphrase, err := readPassword(fmt.Sprintf("GPG Passphrase for key %q: ", name))
+		if err != nil {
+			return nil, err
+		}
+
+		if err := k.PrivateKey.Decrypt(passphrase); err != nil {
+			return nil, fmt.Errorf("decrypting private key %q failed: %v", name, err)
+		}
+		return nil, nil
+	}
+
+	return nil, errors.New("no private key found to decrypt the message")
+}
+
+// readPassword reads a password from the terminal without echoing it.
+// We prefer the controlling terminal so stdin stays free for data.
+func readPassword(prompt string) ([]byte, error) {
+	in := os.Stdin
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		defer tty.Close()
+		in = tty
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	defer fmt.Fprintln(os.Stderr)
+
+	fd := int(in.Fd())
+	if terminal.IsTerminal(fd) {
+		return terminal.ReadPassword(fd)
+	}
+
+	// Not a terminal, read a single line.
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && len(line) == 0 {
+		return nil, fmt.Errorf("reading passphrase failed: %v", err)
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+const historyHelp = `Show the previous values of a secret.`
+
+func (cmd *historyCommand) Name() string      { return "history" }
+func (cmd *historyCommand) Args() string      { return "[OPTIONS] KEY" }
+func (cmd *historyCommand) ShortHelp() string { return historyHelp }
+func (cmd *historyCommand) LongHelp() string  { return historyHelp }
+func (cmd *historyCommand) Hidden() bool      { return false }
+
+func (cmd *historyCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.reveal, "show-values", false, "show the secret values instead of masking them")
+	fs.BoolVar(&cmd.reveal, "reveal", false, "show the secret values instead of masking them")
+}
+
+type historyCommand struct {
+	reveal bool
+}
+
+func (cmd *historyCommand) Run(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return errors.New("must pass a key")
+	}
+
+	key := args[0]
+	sec, ok := s.Secrets[key]
+	if !ok {
+		return fmt.Errorf("secret for key %s does not exist", key)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 20, 1, 3, ' ', 0)
+
+	// print header
+	fmt.Fprintln(w, "VERSION\tUPDATED\tVALUE")
+
+	// print the newest version first
+	current := sec.Version
+	if current == 0 {
+		current = 1
+	}
+	fmt.Fprintf(w, "%d (current)\t%s\t%s\n", current, formatTime(sec.UpdatedAt), maskValue(sec.Value, cmd.reveal))
+	for i := len(sec.History) - 1; i >= 0; i-- {
+		v := sec.History[i]
+		fmt.Fprintf(w, "%d\t%s\t%s\n", v.Version, formatTime(v.UpdatedAt), maskValue(v.Value, cmd.reveal))
+	}
+
+	w.Flush()
+	return nil
+}
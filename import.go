@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const importHelp = `Import secrets from other password managers.`
+
+const importLongHelp = importHelp + `
+
+Formats:
+
+  pass [DIR]      a pass password store, defaults to $PASSWORD_STORE_DIR or
+                  ~/.password-store
+  kdbx FILE       a KeePass or KeePassXC database, asks for the master password
+  bitwarden FILE  an unencrypted Bitwarden JSON export
+  csv FILE        a 1Password, LastPass, Bitwarden, or browser CSV export
+  dotenv FILE     a .env file, the variables are saved under --prefix like
+                  com.myapp.prod.database_url for DATABASE_URL
+
+The format can be given as the first argument or with --format. Entries of
+password managers are saved under the domain of their URL and their username,
+like com.github.jessfraz, or their folders and title if they have no URL. The
+username and URL are kept in the note and TOTP seeds are saved as KEY.totp
+for use with pony otp.
+
+All the secrets are imported in a single write. Secrets that already exist are
+handled according to --conflict: skip keeps the existing secret, overwrite
+replaces it and keeps the old value in its history, and rename imports the
+secret under a new key like KEY-2.`
+
+func (cmd *importCommand) Name() string      { return "import" }
+func (cmd *importCommand) Args() string      { return "[OPTIONS] [FORMAT] [PATH]" }
+func (cmd *importCommand) ShortHelp() string { return importHelp }
+func (cmd *importCommand) LongHelp() string  { return importLongHelp }
+func (cmd *importCommand) Hidden() bool      { return false }
+
+func (cmd *importCommand) Register(fs *flag.FlagSet) {
+	cmd.fs = fs
+	fs.StringVar(&cmd.format, "format", "", "format to import: "+strings.Join(importFormats(), ", "))
+	fs.BoolVar(&cmd.dryRun, "dry-run", false, "only print what would be imported")
+	fs.StringVar(&cmd.conflict, "conflict", conflictSkip, "what to do with secrets that already exist: skip, overwrite, or rename")
+	fs.Var(&cmd.maps, "map", "map a path prefix to a key prefix as PATH=KEY, like work=com.example, comma separated or repeated")
+	fs.StringVar(&cmd.tld, "tld", "com", "top level domain to prefix keys with when the first path element is not a domain name")
+	fs.Var(&cmd.tags, "tag", "tag to add to every imported secret, comma separated or repeated")
+	fs.StringVar(&cmd.prefix, "prefix", "", "key prefix to save the variables of a dotenv file under, like com.myapp.prod.")
+}
+
+type importCommand struct {
+	format   string
+	dryRun   bool
+	conflict string
+	maps     listFlag
+	tld      string
+	tags     listFlag
+	prefix   string
+
+	// fs is the flag set the command was registered with.
+	fs *flag.FlagSet
+}
+
+const (
+	conflictSkip      = "skip"
+	conflictOverwrite = "overwrite"
+	conflictRename    = "rename"
+)
+
+// importEntry is a single secret read from another password manager.
+type importEntry struct {
+	Key   string
+	Value string
+	Note  string
+	Tags  []string
+}
+
+// login is an entry of a password manager.
+type login struct {
+	// Key is set for entries exported by pony.
+	Key string
+
+	Title    string
+	URL      string
+	Username string
+	Password string
+	Notes    string
+	TOTP     string
+	Groups   []string
+	Tags     []string
+}
+
+// importer reads the secrets at the path.
+type importer func(cmd *importCommand, path string) ([]importEntry, error)
+
+// importers are the supported formats.
+var importers = map[string]importer{
+	"bitwarden": importBitwarden,
+	"csv":       importCSV,
+	"dotenv":    importDotenv,
+	"kdbx":      importKDBX,
+	"pass":      importPass,
+}
+
+func (cmd *importCommand) Run(ctx context.Context, args []string) error {
+	args, err := parseTrailingFlags(cmd.fs, &importCommand{}, args)
+	if err != nil {
+		return err
+	}
+
+	format := cmd.format
+	if len(format) == 0 {
+		if len(args) < 1 {
+			return errors.New("must pass the format to import")
+		}
+		format, args = args[0], args[1:]
+	}
+	imp, ok := importers[format]
+	if !ok {
+		return fmt.Errorf("unknown import format %q, must be one of %s", format, strings.Join(importFormats(), ", "))
+	}
+
+	switch cmd.conflict {
+	case conflictSkip, conflictOverwrite, conflictRename:
+	default:
+		return fmt.Errorf("unknown conflict policy %q, must be one of skip, overwrite, or rename", cmd.conflict)
+	}
+
+	path := ""
+	if len(args) > 0 {
+		path = args[0]
+	}
+	entries, err := imp(cmd, path)
+	if err != nil {
+		return err
+	}
+
+	return cmd.save(entries)
+}
+
+// save adds the entries to the secrets and writes the secrets file once.
+func (cmd *importCommand) save(entries []importEntry) error {
+	verb := "Imported"
+	if cmd.dryRun {
+		verb = "Would import"
+	}
+
+	var imported, skipped, conflicting int
+	for _, e := range entries {
+		if len(e.Key) == 0 {
+			skipped++
+			continue
+		}
+
+		key := e.Key
+		_, exists := s.Secrets[key]
+		if exists {
+			conflicting++
+			switch cmd.conflict {
+			case conflictSkip:
+				fmt.Printf("Skipped %s, it already exists\n", key)
+				skipped++
+				continue
+			case conflictRename:
+				for n := 2; exists; n++ {
+					key = fmt.Sprintf("%s-%d", e.Key, n)
+					_, exists = s.Secrets[key]
+				}
+			}
+		}
+
+		if err := s.setSecret(key, e.Value, true, e.Note, append(e.Tags, cmd.tags.values...)); err != nil {
+			return err
+		}
+		imported++
+		if key != e.Key {
+			fmt.Printf("%s %s as %s\n", verb, e.Key, key)
+		} else {
+			fmt.Printf("%s %s\n", verb, key)
+		}
+	}
+
+	fmt.Printf("%s %d secret(s), skipped %d, %d conflicting\n", verb, imported, skipped, conflicting)
+
+	if cmd.dryRun || imported == 0 {
+		return nil
+	}
+	return writeSecretsFile(file, s)
+}
+
+// loginEntries converts the login into entries, with a second entry for the
+// TOTP seed if it has one.
+func (cmd *importCommand) loginEntries(l login) []importEntry {
+	if len(l.Password) == 0 {
+		return []importEntry{{}}
+	}
+
+	notes := []string{}
+	if len(l.Username) > 0 {
+		notes = append(notes, "username: "+l.Username)
+	}
+	if len(l.URL) > 0 {
+		notes = append(notes, "url: "+l.URL)
+	}
+	if n := strings.TrimSpace(l.Notes); len(n) > 0 {
+		notes = append(notes, n)
+	}
+
+	key := cmd.loginKey(l)
+	entries := []importEntry{{
+		Key:   key,
+		Value: l.Password,
+		Note:  strings.Join(notes, "\n"),
+		Tags:  l.Tags,
+	}}
+	if len(l.TOTP) > 0 && len(key) > 0 {
+		entries = append(entries, importEntry{Key: key + ".totp", Value: l.TOTP, Tags: l.Tags})
+	}
+	return entries
+}
+
+// loginKey returns the key for the login, made from the domain of its URL
+// and its username, or its folders and title if it has no URL. Entries
+// exported by pony keep their key.
+func (cmd *importCommand) loginKey(l login) string {
+	if len(l.Key) > 0 {
+		return l.Key
+	}
+
+	name := l.Username
+	if len(name) == 0 {
+		name = l.Title
+	}
+
+	// LastPass exports secure notes with the URL http://sn.
+	rawurl := l.URL
+	if rawurl == "http://sn" {
+		rawurl = ""
+	}
+	if len(rawurl) > 0 && !strings.Contains(rawurl, "://") {
+		rawurl = "https://" + rawurl
+	}
+	if u, err := url.Parse(rawurl); err == nil && len(u.Hostname()) > 0 && len(name) > 0 {
+		return cmd.pathKey(strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.") + "/" + keyElement(name))
+	}
+
+	if len(l.Title) == 0 {
+		return ""
+	}
+	parts := []string{}
+	for _, g := range append(l.Groups, l.Title) {
+		if e := keyElement(g); len(e) > 0 {
+			parts = append(parts, e)
+		}
+	}
+	return cmd.pathKey(strings.Join(parts, "/"))
+}
+
+// keyElement makes a title or folder name usable as part of a key.
+func keyElement(name string) string {
+	name = strings.Join(strings.Fields(strings.ToLower(name)), "-")
+	return strings.Replace(name, "/", "-", -1)
+}
+
+// pathKey maps a path like github/jessfraz to a key like
+// com.github.jessfraz. The first element is reversed if it is a domain name,
+// github.com/jessfraz becomes com.github.jessfraz as well.
+func (cmd *importCommand) pathKey(path string) string {
+	path = strings.Trim(path, "/")
+
+	// Use the longest matching --map.
+	match, prefix := "", ""
+	for _, m := range cmd.maps.values {
+		i := strings.Index(m, "=")
+		if i < 1 {
+			continue
+		}
+		from := strings.Trim(m[:i], "/")
+		if (path == from || strings.HasPrefix(path, from+"/")) && len(from) > len(match) {
+			match, prefix = from, m[i+1:]
+		}
+	}
+	if len(match) > 0 {
+		rest := strings.Trim(strings.TrimPrefix(path, match), "/")
+		if len(rest) == 0 {
+			return prefix
+		}
+		return strings.TrimSuffix(prefix, ".") + "." + strings.Replace(rest, "/", ".", -1)
+	}
+
+	parts := strings.Split(path, "/")
+	if strings.Contains(parts[0], ".") {
+		labels := strings.Split(parts[0], ".")
+		for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+			labels[i], labels[j] = labels[j], labels[i]
+		}
+		parts[0] = strings.Join(labels, ".")
+	} else if len(cmd.tld) > 0 {
+		parts[0] = cmd.tld + "." + parts[0]
+	}
+	return strings.Join(parts, ".")
+}
+
+// importFormats returns the names of the supported formats.
+func importFormats() []string {
+	formats := []string{}
+	for name := range importers {
+		formats = append(formats, name)
+	}
+	sort.Strings(formats)
+	return formats
+}
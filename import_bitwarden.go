@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// bitwardenExport is the unencrypted JSON export of Bitwarden.
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Items []struct {
+		Type     int    `json:"type"`
+		Name     string `json:"name"`
+		Notes    string `json:"notes"`
+		FolderID string `json:"folderId"`
+		Login    struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			TOTP     string `json:"totp"`
+			URIs     []struct {
+				URI string `json:"uri"`
+			} `json:"uris"`
+		} `json:"login"`
+	} `json:"items"`
+}
+
+// Bitwarden item types.
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+)
+
+// importBitwarden reads the logins and secure notes of a Bitwarden JSON
+// export. Cards and identities are skipped.
+func importBitwarden(cmd *importCommand, filename string) ([]importEntry, error) {
+	if len(filename) == 0 {
+		return nil, errors.New("must pass the bitwarden export to import")
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var export bitwardenExport
+	if err := json.Unmarshal(b, &export); err != nil {
+		return nil, fmt.Errorf("parsing bitwarden export %s failed: %v", filename, err)
+	}
+	if export.Encrypted {
+		return nil, errors.New("encrypted bitwarden exports are not supported, export as unencrypted json instead")
+	}
+
+	folders := map[string]string{}
+	for _, f := range export.Folders {
+		folders[f.ID] = f.Name
+	}
+
+	entries := []importEntry{}
+	for _, item := range export.Items {
+		l := login{Title: item.Name, Notes: item.Notes}
+		if folder, ok := folders[item.FolderID]; ok {
+			l.Groups = splitFolder(folder)
+		}
+
+		switch item.Type {
+		case bitwardenLogin:
+			l.Username = item.Login.Username
+			l.Password = item.Login.Password
+			l.TOTP = item.Login.TOTP
+			if len(item.Login.URIs) > 0 {
+				l.URL = item.Login.URIs[0].URI
+			}
+		case bitwardenSecureNote:
+			// The note is the secret.
+			l.Password, l.Notes = item.Notes, ""
+		}
+
+		entries = append(entries, cmd.loginEntries(l)...)
+	}
+
+	return entries, nil
+}
+
+// splitFolder splits a nested folder name like work/aws.
+func splitFolder(folder string) []string {
+	parts := []string{}
+	for _, p := range strings.Split(folder, "/") {
+		if p = strings.TrimSpace(p); len(p) > 0 {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// csvColumns maps the column names used by the CSV exports of 1Password,
+// LastPass, Bitwarden, browsers, and pony to the fields of a login.
+var csvColumns = map[string]string{
+	"key":            "key",
+	"title":          "title",
+	"name":           "title",
+	"url":            "url",
+	"website":        "url",
+	"login_uri":      "url",
+	"username":       "username",
+	"login_username": "username",
+	"password":       "password",
+	"login_password": "password",
+	"value":          "password",
+	"notes":          "notes",
+	"note":           "notes",
+	"extra":          "notes",
+	"totp":           "totp",
+	"login_totp":     "totp",
+	"otpauth":        "totp",
+	"grouping":       "group",
+	"folder":         "group",
+	"tags":           "tags",
+}
+
+// importCSV reads the logins of a CSV export. The first row has to name the
+// columns and there has to be a password column.
+func importCSV(cmd *importCommand, filename string) ([]importEntry, error) {
+	if len(filename) == 0 {
+		return nil, errors.New("must pass the csv file to import")
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parsing csv %s failed: %v", filename, err)
+	}
+	if len(rows) < 1 {
+		return nil, fmt.Errorf("%s is empty", filename)
+	}
+
+	// Find the columns from the header.
+	columns := map[string]int{}
+	for i, name := range rows[0] {
+		// Spreadsheets like to start the file with a byte order mark.
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if field, ok := csvColumns[name]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["password"]; !ok {
+		return nil, fmt.Errorf("%s has no password column", filename)
+	}
+
+	entries := []importEntry{}
+	for _, row := range rows[1:] {
+		raw := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		get := func(field string) string {
+			return strings.TrimSpace(raw(field))
+		}
+		entries = append(entries, cmd.loginEntries(login{
+			Key:      get("key"),
+			Title:    get("title"),
+			URL:      get("url"),
+			Username: get("username"),
+			Password: raw("password"),
+			Notes:    get("notes"),
+			TOTP:     get("totp"),
+			Groups:   splitFolder(strings.Replace(get("group"), "\\", "/", -1)),
+			Tags:     splitList(get("tags")),
+		})...)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// importDotenv reads the variables of a dotenv file and saves them under the
+// prefix with dotenvKey, so exporting the prefix gives back the same names.
+func importDotenv(cmd *importCommand, filename string) ([]importEntry, error) {
+	if len(filename) == 0 {
+		return nil, errors.New("must pass the dotenv file to import")
+	}
+	if len(cmd.prefix) == 0 {
+		return nil, errors.New("must pass the key prefix to save the variables under with --prefix")
+	}
+
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := parseDotenv(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s failed: %v", filename, err)
+	}
+
+	prefix := strings.TrimSuffix(cmd.prefix, ".") + "."
+	entries := []importEntry{}
+	for _, v := range vars {
+		key, err := dotenvKey(v.Name, prefix)
+		if err != nil {
+			// Keep going, one name that can not be exported back should not
+			// stop the import.
+			logrus.Warnf("importing %s from %s failed, skipping it: %v", v.Name, filename, err)
+			entries = append(entries, importEntry{})
+			continue
+		}
+		entries = append(entries, importEntry{
+			Key:   key,
+			Value: v.Value,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// importKDBX reads the entries of a KeePass database. The entries in the
+// recycle bin are left out.
+func importKDBX(cmd *importCommand, filename string) ([]importEntry, error) {
+	if len(filename) == 0 {
+		return nil, errors.New("must pass the kdbx file to import")
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	password, err := promptPassword(fmt.Sprintf("Master password for %s: ", filename), false)
+	if err != nil {
+		return nil, err
+	}
+
+	db := gokeepasslib.NewDatabase()
+	db.Credentials = gokeepasslib.NewPasswordCredentials(password)
+	if err := gokeepasslib.NewDecoder(f).Decode(db); err != nil {
+		return nil, fmt.Errorf("opening %s failed: %v", filename, err)
+	}
+	if err := db.UnlockProtectedEntries(); err != nil {
+		return nil, fmt.Errorf("unlocking the entries of %s failed: %v", filename, err)
+	}
+
+	if db.Content == nil || db.Content.Root == nil {
+		return nil, fmt.Errorf("%s has no entries", filename)
+	}
+	meta := db.Content.Meta
+	if meta == nil {
+		meta = &gokeepasslib.MetaData{}
+	}
+
+	entries := []importEntry{}
+	var walk func(g gokeepasslib.Group, groups []string)
+	walk = func(g gokeepasslib.Group, groups []string) {
+		if meta.RecycleBinEnabled.Bool && g.UUID.Compare(meta.RecycleBinUUID) {
+			return
+		}
+		for _, e := range g.Entries {
+			entries = append(entries, cmd.loginEntries(login{
+				Key:      e.GetContent(kdbxKeyField),
+				Title:    e.GetTitle(),
+				URL:      e.GetContent("URL"),
+				Username: e.GetContent("UserName"),
+				Password: e.GetPassword(),
+				Notes:    e.GetContent("Notes"),
+				TOTP:     e.GetContent("otp"),
+				Groups:   groups,
+				Tags:     splitKDBXTags(e.Tags),
+			})...)
+		}
+		for _, sub := range g.Groups {
+			walk(sub, append(groups[:len(groups):len(groups)], sub.Name))
+		}
+	}
+	// The name of the root group is the name of the database, leave it out
+	// of the keys.
+	for _, g := range db.Content.Root.Groups {
+		walk(g, []string{})
+	}
+
+	return entries, nil
+}
+
+// splitKDBXTags splits the tags of an entry, KeePass separates them with
+// semicolons and KeePassXC with commas.
+func splitKDBXTags(tags string) []string {
+	return splitList(strings.Replace(tags, ";", ",", -1))
+}
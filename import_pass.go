@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jessfraz/pony/gpg"
+	"github.com/sirupsen/logrus"
+)
+
+// importPass reads the secrets from a pass password store. The first line of
+// each file is the value and the other lines are the note.
+func importPass(cmd *importCommand, dir string) ([]importEntry, error) {
+	dir, err := passStoreDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []importEntry{}
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() && fi.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !fi.Mode().IsRegular() || filepath.Ext(path) != ".gpg" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(strings.TrimSuffix(rel, ".gpg"))
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		b, err = gpg.DecryptBytes(b)
+		if err != nil {
+			// Keep going, one broken file should not stop the import.
+			logrus.Warnf("decrypting %s failed, skipping it: %v", path, err)
+			entries = append(entries, importEntry{})
+			return nil
+		}
+
+		value, note := splitPassEntry(string(b))
+		entries = append(entries, importEntry{
+			Key:   cmd.pathKey(name),
+			Value: value,
+			Note:  note,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading pass store %s failed: %v", dir, err)
+	}
+
+	return entries, nil
+}
+
+// passStoreDir returns the directory of the pass password store, defaulting
+// to $PASSWORD_STORE_DIR or ~/.password-store.
+func passStoreDir(dir string) (string, error) {
+	if len(dir) == 0 {
+		dir = os.Getenv("PASSWORD_STORE_DIR")
+	}
+	if len(dir) == 0 {
+		home, err := getHome()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".password-store")
+	}
+	return dir, nil
+}
+
+// splitPassEntry splits a pass entry into the password on the first line
+// and the rest.
+func splitPassEntry(content string) (string, string) {
+	content = strings.Replace(content, "\r\n", "\n", -1)
+	lines := strings.SplitN(content, "\n", 2)
+	if len(lines) < 2 {
+		return lines[0], ""
+	}
+	return lines[0], strings.TrimSpace(lines[1])
+}
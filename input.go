@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// readValue reads a secret value so it does not have to be passed on the
+// command line where it ends up in the shell history and `ps` output. If
+// filename is set the value is read from the file, otherwise it is prompted
+// for when stdin is a terminal or read from stdin when it is not.
+func readValue(filename string) (string, error) {
+	if len(filename) > 0 {
+		f, err := os.Open(filename)
+		if err != nil {
+			return "", fmt.Errorf("reading value from %s failed: %v", filename, err)
+		}
+		defer f.Close()
+		return readValueFrom(f, filename)
+	}
+
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		return promptValue(fd)
+	}
+
+	return readValueFrom(os.Stdin, "stdin")
+}
+
+// readValueFrom reads a value from r. This supports multi-line values like
+// PEM keys, only the final newline is removed since most tools add one. The
+// same value is read from a file and from stdin.
+func readValueFrom(r io.Reader, source string) (string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("reading value from %s failed: %v", source, err)
+	}
+
+	value := strings.TrimSuffix(string(b), "\n")
+	value = strings.TrimSuffix(value, "\r")
+	if len(value) == 0 {
+		return "", fmt.Errorf("value read from %s is empty", source)
+	}
+	return value, nil
+}
+
+// promptValue asks for the value twice on the terminal without echoing it.
+func promptValue(fd int) (string, error) {
+	fmt.Fprint(os.Stderr, "Value: ")
+	value, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading value failed: %v", err)
+	}
+	if len(value) == 0 {
+		return "", errors.New("value is empty")
+	}
+
+	fmt.Fprint(os.Stderr, "Confirm value: ")
+	confirm, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading value failed: %v", err)
+	}
+
+	if string(value) != string(confirm) {
+		return "", errors.New("values do not match")
+	}
+	return string(value), nil
+}
+
+// promptPassword asks for a password on the terminal without echoing it,
+// twice if it has to be confirmed. When stdin is not a terminal the first
+// line of stdin is used.
+func promptPassword(prompt string, confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("reading password from stdin failed: %v", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading password failed: %v", err)
+	}
+	if !confirm {
+		return string(password), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Confirm "+strings.ToLower(prompt[:1])+prompt[1:])
+	again, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading password failed: %v", err)
+	}
+	if string(password) != string(again) {
+		return "", errors.New("passwords do not match")
+	}
+	return string(password), nil
+}
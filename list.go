@@ -7,7 +7,9 @@ import (
 	"os"
 	"regexp"
 	"sort"
+	"strings"
 	"text/tabwriter"
+	"time"
 )
 
 const listHelp = `List secrets.`
@@ -21,40 +23,89 @@ func (cmd *listCommand) Hidden() bool      { return false }
 func (cmd *listCommand) Register(fs *flag.FlagSet) {
 	fs.StringVar(&cmd.filter, "f", "", "filter secrets keys by a regular expression")
 	fs.StringVar(&cmd.filter, "filter", "", "filter secrets keys by a regular expression")
+	fs.Var(&cmd.tags, "tag", "only list secrets with the tags, comma separated or repeated")
+	fs.BoolVar(&cmd.reveal, "show-values", false, "show the secret values instead of masking them")
+	fs.BoolVar(&cmd.reveal, "reveal", false, "show the secret values instead of masking them")
+	fs.BoolVar(&cmd.keysOnly, "keys-only", false, "only list the secret keys")
+	fs.StringVar(&cmd.format, "format", "", "output format: json, yaml, dotenv, csv, or a Go template like '{{.Key}}={{.Value}}'")
 }
 
 type listCommand struct {
-	filter string
+	filter   string
+	tags     listFlag
+	reveal   bool
+	keysOnly bool
+	format   string
 }
 
 func (cmd *listCommand) Run(ctx context.Context, args []string) error {
+	keys := filterKeys(cmd.filter, cmd.tags.values)
+
+	if len(cmd.format) > 0 {
+		records := []secretRecord{}
+		for _, key := range keys {
+			if cmd.keysOnly {
+				records = append(records, secretRecord{Key: key})
+				continue
+			}
+			records = append(records, newSecretRecord(key, s.Secrets[key], maskValue(s.Secrets[key].Value, cmd.reveal)))
+		}
+		return writeRecords(os.Stdout, cmd.format, records, false)
+	}
+
 	w := tabwriter.NewWriter(os.Stdout, 20, 1, 3, ' ', 0)
 
 	// print header
-	fmt.Fprintln(w, "KEY\tVALUE")
-
-	// print the keys alphabetically
-	printSorted := func(m map[string]string) {
-		mk := make([]string, len(m))
-		i := 0
-		for k := range m {
-			mk[i] = k
-			i++
+	if !cmd.keysOnly {
+		fmt.Fprintln(w, "KEY\tVALUE\tTAGS\tUPDATED\tNOTE")
+	}
+
+	for _, key := range keys {
+		if cmd.keysOnly {
+			fmt.Fprintln(w, key)
+			continue
 		}
-		sort.Strings(mk)
+		sec := s.Secrets[key]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", key, maskValue(sec.Value, cmd.reveal), strings.Join(sec.Tags, ","), formatTime(sec.UpdatedAt), sec.Note)
+	}
 
-		for _, key := range mk {
-			if len(cmd.filter) > 0 {
-				if ok, _ := regexp.MatchString(cmd.filter, key); !ok {
-					continue
-				}
+	w.Flush()
+	return nil
+}
+
+// filterKeys returns the keys alphabetically that match the regular
+// expression and have all the tags.
+func filterKeys(filter string, tags []string) []string {
+	keys := []string{}
+	for key, sec := range s.Secrets {
+		if len(filter) > 0 {
+			if ok, _ := regexp.MatchString(filter, key); !ok {
+				continue
 			}
-			fmt.Fprintf(w, "%s\t%s\n", key, m[key])
 		}
+		if !sec.hasTags(tags) {
+			continue
+		}
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
+	return keys
+}
 
-	printSorted(s.Secrets)
+// formatTime formats the time for display, secrets migrated from older files
+// do not have timestamps.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
 
-	w.Flush()
-	return nil
+// maskValue hides the value unless it should be revealed. The mask always has
+// the same length so it does not give away the length of the value.
+func maskValue(value string, reveal bool) string {
+	if reveal {
+		return value
+	}
+	return "********"
 }
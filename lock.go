@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// errLocked is returned by tryLock if another process holds the lock.
+var errLocked = errors.New("file is locked")
+
+// lockFile takes an advisory lock on the lock file next to filename so that
+// concurrent pony invocations do not overwrite each others changes. Readers
+// take a shared lock and writers an exclusive one. It waits up to timeout for
+// other processes to release the lock.
+func lockFile(filename string, exclusive bool, timeout time.Duration) (*os.File, error) {
+	name := filename + ".lock"
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file %s failed: %v", name, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := tryLock(f, exclusive)
+		if err == nil {
+			return f, nil
+		}
+		if err != errLocked {
+			f.Close()
+			return nil, fmt.Errorf("locking %s failed: %v", name, err)
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("timed out after %s waiting for the lock on %s, another pony process is using the secrets file", timeout, name)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// unlockFile releases the lock taken with lockFile.
+func unlockFile(f *os.File) error {
+	if f == nil {
+		return nil
+	}
+	if err := unlock(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
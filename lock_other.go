@@ -0,0 +1,17 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package main
+
+import (
+	"os"
+)
+
+// File locking is not supported on this platform.
+func tryLock(f *os.File, exclusive bool) error {
+	return nil
+}
+
+func unlock(f *os.File) error {
+	return nil
+}
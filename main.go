@@ -4,23 +4,51 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/user"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/genuinetools/pkg/cli"
+	"github.com/jessfraz/pony/age"
+	"github.com/jessfraz/pony/gpg"
 	"github.com/jessfraz/pony/version"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	defaultFilestore string = ".pony"
-	defaultGPGPath   string = ".gnupg/"
+	defaultGPGPath   string = ".gnupg"
+
+	defaultAgeIdentity string = ".config/age/keys.txt"
 )
 
+// readOnlyCommands are the commands that never write to the secrets file
+// and only need a shared lock.
+var readOnlyCommands = map[string]bool{
+	"agent":   true,
+	"exec":    true,
+	"export":  true,
+	"get":     true,
+	"history": true,
+	"ls":      true,
+	"render":  true,
+}
+
 var (
-	file  string
-	keyid string
+	file         string
+	keyids       listFlag
+	backend      string
+	gpgBackend   string
+	gpgPath      string
+	ageIdentity  string
+	backups      int
+	historyDepth int
+	lockTimeout  time.Duration
+
+	lock *os.File
 
 	s secretFile
 
@@ -28,6 +56,12 @@ var (
 )
 
 func main() {
+	// git runs git-credential-pony for `credential.helper pony`, so pony
+	// symlinked under that name acts as the git-credential command.
+	if filepath.Base(os.Args[0]) == "git-credential-pony" {
+		os.Args = append([]string{"pony", "git-credential"}, os.Args[1:]...)
+	}
+
 	// Create a new cli program.
 	p := cli.NewProgram()
 	p.Name = "pony"
@@ -38,9 +72,25 @@ func main() {
 
 	// Build the list of available commands.
 	p.Commands = []cli.Command{
+		&agentCommand{},
+		&copyCommand{},
 		&createCommand{},
+		&editCommand{},
+		&execCommand{},
+		&exportCommand{},
+		&generateCommand{},
 		&getCommand{},
+		&gitCredentialCommand{},
+		&historyCommand{},
+		&importCommand{},
 		&listCommand{},
+		&moveCommand{},
+		&otpCommand{},
+		&recipientsCommand{},
+		&recoveryCommand{},
+		&rekeyCommand{},
+		&renderCommand{},
+		&rollbackCommand{},
 		&removeCommand{},
 	}
 
@@ -48,7 +98,19 @@ func main() {
 	p.FlagSet = flag.NewFlagSet("global", flag.ExitOnError)
 	p.FlagSet.StringVar(&file, "file", fmt.Sprintf("%s/%s", homeShortcut, defaultFilestore), "file to use for saving encrypted secrets")
 
-	p.FlagSet.StringVar(&keyid, "keyid", os.Getenv("PONY_KEYID"), "optionally set specific gpg keyid/fingerprint to use for encryption & decryption (or env var PONY_KEYID)")
+	keyids = listFlag{values: splitList(os.Getenv("PONY_KEYID"))}
+	p.FlagSet.Var(&keyids, "keyid", "optionally set specific gpg keyids/fingerprints or age recipients to encrypt to, comma separated or repeated (or env var PONY_KEYID)")
+
+	p.FlagSet.StringVar(&backend, "backend", os.Getenv("PONY_BACKEND"), "encryption backend to use for writing, gpg or age, defaults to the one the file was encrypted with (or env var PONY_BACKEND)")
+
+	p.FlagSet.StringVar(&gpgBackend, "gpg-backend", getEnvDefault("PONY_GPG_BACKEND", string(gpg.Native)), "gpg backend to use, native or exec (or env var PONY_GPG_BACKEND)")
+	p.FlagSet.StringVar(&gpgPath, "gpgpath", getEnvDefault("PONY_GPGPATH", getEnvDefault("GNUPGHOME", fmt.Sprintf("%s/%s", homeShortcut, defaultGPGPath))), "directory the native gpg backend reads pubring.gpg and secring.gpg from (or env var PONY_GPGPATH, GNUPGHOME)")
+	p.FlagSet.StringVar(&ageIdentity, "age-identity", getEnvDefault("PONY_AGE_IDENTITY", fmt.Sprintf("%s/%s", homeShortcut, defaultAgeIdentity)), "age identity file to use for decryption (or env var PONY_AGE_IDENTITY)")
+
+	p.FlagSet.IntVar(&backups, "backups", 3, "number of rolling backups of the secrets file to keep, saved as FILE.bak.N")
+
+	p.FlagSet.IntVar(&historyDepth, "history-depth", 10, "number of previous values to keep for each secret")
+	p.FlagSet.DurationVar(&lockTimeout, "lock-timeout", 10*time.Second, "how long to wait for other pony processes to release the secrets file")
 
 	p.FlagSet.BoolVar(&debug, "d", false, "enable debug logging")
 	p.FlagSet.BoolVar(&debug, "debug", false, "enable debug logging")
@@ -68,6 +130,45 @@ func main() {
 		// Set the file variable.
 		file = strings.Replace(file, homeShortcut, home, 1)
 
+		// Validate the backend.
+		if len(backend) > 0 {
+			if _, err := getCipher(backend); err != nil {
+				return err
+			}
+		}
+
+		// Setup the age identity file.
+		age.IdentityFile = strings.Replace(ageIdentity, homeShortcut, home, 1)
+
+		// Setup the gpg backend.
+		gpg.Use, err = gpg.ParseBackend(gpgBackend)
+		if err != nil {
+			return err
+		}
+		gpg.Home = strings.Replace(gpgPath, homeShortcut, home, 1)
+
+		// Use the agent for the commands it serves, it already holds the
+		// decrypted secrets and takes care of locking.
+		if socket := os.Getenv("PONY_AGENT_SOCK"); len(socket) > 0 && agentCommands[os.Args[1]] {
+			agent, err = dialAgent(socket)
+			if err == nil {
+				// Only ls needs all the secrets, the other commands send
+				// their own requests.
+				if os.Args[1] == "ls" {
+					s, err = agent.list()
+				}
+				return err
+			}
+			logrus.Debugf("connecting to the agent failed, using the secrets file: %v", err)
+		}
+
+		// Lock the secrets file for the whole read-modify-write cycle.
+		// Commands that only read take a shared lock.
+		lock, err = lockFile(file, !readOnlyCommands[os.Args[1]], lockTimeout)
+		if err != nil {
+			return err
+		}
+
 		// Create our secrets file if it does not exist.
 		if _, err := os.Stat(file); os.IsNotExist(err) {
 			if err := writeSecretsFile(file, secretFile{}); err != nil {
@@ -85,6 +186,14 @@ func main() {
 		return nil
 	}
 
+	// Set the after function.
+	p.After = func(ctx context.Context) error {
+		if agent != nil {
+			return agent.Close()
+		}
+		return unlockFile(lock)
+	}
+
 	// Run our program.
 	p.Run()
 }
@@ -101,3 +210,73 @@ func getHome() (string, error) {
 	}
 	return u.HomeDir, nil
 }
+
+func getEnvDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// listFlag is a flag.Value for a list of strings that can be given comma
+// separated or by repeating the flag. Values given on the command line
+// replace the default.
+type listFlag struct {
+	values  []string
+	changed bool
+}
+
+func (l *listFlag) String() string {
+	return strings.Join(l.values, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	if !l.changed {
+		l.values = nil
+		l.changed = true
+	}
+	l.values = append(l.values, splitList(value)...)
+	return nil
+}
+
+// splitList splits a comma separated list and drops the empty items.
+func splitList(value string) []string {
+	l := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+// parseTrailingFlags parses the flags of a command that are given after its
+// arguments, like `pony import dotenv .env --prefix com.myapp.prod.`, and
+// returns the arguments. flag stops parsing at the first argument, so the
+// rest is parsed with the flags of a new command and set on fs, the flag set
+// the command was registered with. Global flags still have to come first.
+func parseTrailingFlags(fs *flag.FlagSet, fresh cli.Command, args []string) ([]string, error) {
+	trailing := flag.NewFlagSet(fresh.Name(), flag.ContinueOnError)
+	trailing.SetOutput(ioutil.Discard)
+	fresh.Register(trailing)
+
+	positional := []string{}
+	for len(args) > 0 {
+		if err := trailing.Parse(args); err != nil {
+			return nil, err
+		}
+		args = trailing.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+
+	var err error
+	trailing.Visit(func(f *flag.Flag) {
+		if err == nil {
+			err = fs.Set(f.Name, f.Value.String())
+		}
+	})
+	return positional, err
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// formatVersion is the version of the secrets filestore written by this
+// version of pony. Bump it and register a migration whenever secretFile
+// changes in a way older versions of pony can not read.
+const formatVersion = 3
+
+// migration upgrades the decrypted contents of a secrets file by one
+// version.
+type migration func(data map[string]interface{}) error
+
+// migrations holds the migration from each version to the next one.
+var migrations = map[int]migration{
+	1: migrateFlatSecrets,
+	2: migrateRecoveryCodes,
+}
+
+// errNewerFormat returns the error for a file written by a newer version of
+// pony.
+func errNewerFormat(version int) error {
+	return fmt.Errorf("the secrets file was written by a newer version of pony (format v%d, this version supports up to v%d), upgrade pony to read it", version, formatVersion)
+}
+
+// migrate upgrades the decrypted contents of a secrets file to the current
+// format version.
+func migrate(b []byte) ([]byte, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	// Files without a version are version 1.
+	version := 1
+	if v, ok := data["version"].(float64); ok && v > 0 {
+		version = int(v)
+	}
+	if version > formatVersion {
+		return nil, errNewerFormat(version)
+	}
+	if version == formatVersion {
+		return b, nil
+	}
+
+	for ; version < formatVersion; version++ {
+		m, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration registered from format v%d", version)
+		}
+		if err := m(data); err != nil {
+			return nil, fmt.Errorf("migrating secrets file from format v%d to v%d failed: %v", version, version+1, err)
+		}
+		logrus.Debugf("migrated secrets file from format v%d to v%d", version, version+1)
+	}
+	data["version"] = formatVersion
+
+	return json.Marshal(data)
+}
+
+// migrateFlatSecrets converts the flat map of keys to values of files written
+// before the envelope header existed into secrets with metadata. The header
+// is added on the next write. The migrated secrets do not have timestamps.
+func migrateFlatSecrets(data map[string]interface{}) error {
+	secrets, ok := data["secrets"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for key, v := range secrets {
+		value, ok := v.(string)
+		if !ok {
+			return errors.New("secret for key " + key + " is not a string")
+		}
+		secrets[key] = map[string]interface{}{"value": value}
+	}
+	return nil
+}
+
+// migrateRecoveryCodes splits the comma separated values of keys ending in
+// .recovery into recovery codes that track when they were used.
+func migrateRecoveryCodes(data map[string]interface{}) error {
+	secrets, ok := data["secrets"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for key, v := range secrets {
+		sec, ok := v.(map[string]interface{})
+		if !ok || !isRecoveryKey(key) {
+			continue
+		}
+		value, _ := sec["value"].(string)
+		codes := []interface{}{}
+		for _, code := range newRecoveryCodes(value, nil) {
+			codes = append(codes, map[string]interface{}{"code": code.Code})
+		}
+		sec["recovery"] = codes
+	}
+	return nil
+}
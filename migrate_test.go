@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Fixtures of the decrypted contents of the secrets file in each format
+// version, they all hold the same secrets.
+const (
+	fixtureV1 = `{"secrets":{"com.github.jessfraz.token":"s3cret","com.github.jessfraz.recovery":"aaaa-1111,bbbb-2222"}}`
+
+	fixtureV2 = `{"version":2,"secrets":{` +
+		`"com.github.jessfraz.token":{"value":"s3cret"},` +
+		`"com.github.jessfraz.recovery":{"value":"aaaa-1111,bbbb-2222"}}}`
+
+	fixtureV3 = `{"version":3,"secrets":{` +
+		`"com.github.jessfraz.token":{"value":"s3cret"},` +
+		`"com.github.jessfraz.recovery":{"value":"aaaa-1111,bbbb-2222","recovery":[{"code":"aaaa-1111"},{"code":"bbbb-2222"}]}}}`
+)
+
+func unmarshalFixture(t *testing.T, fixture string) map[string]interface{} {
+	t.Helper()
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(fixture), &data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestMigrations(t *testing.T) {
+	testCases := []struct {
+		from     int
+		fixture  string
+		expected string
+	}{
+		{from: 1, fixture: fixtureV1, expected: fixtureV2},
+		{from: 2, fixture: fixtureV2, expected: fixtureV3},
+	}
+
+	for _, tc := range testCases {
+		m, ok := migrations[tc.from]
+		if !ok {
+			t.Fatalf("no migration registered from v%d", tc.from)
+		}
+
+		data := unmarshalFixture(t, tc.fixture)
+		if err := m(data); err != nil {
+			t.Fatalf("migrating from v%d failed: %v", tc.from, err)
+		}
+
+		// The version is only bumped by migrate.
+		data["version"] = float64(tc.from + 1)
+		expected := unmarshalFixture(t, tc.expected)
+		if !reflect.DeepEqual(data, expected) {
+			t.Errorf("migrating from v%d: expected %v, got %v", tc.from, expected, data)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	expected := unmarshalFixture(t, fixtureV3)
+
+	for _, fixture := range []string{fixtureV1, fixtureV2, fixtureV3} {
+		b, err := migrate([]byte(fixture))
+		if err != nil {
+			t.Fatalf("migrating %s failed: %v", fixture, err)
+		}
+		data := unmarshalFixture(t, string(b))
+		if !reflect.DeepEqual(data, expected) {
+			t.Errorf("migrating %s: expected %v, got %v", fixture, expected, data)
+		}
+
+		// The migrated contents have to be readable as the current format.
+		var s secretFile
+		if err := json.Unmarshal(b, &s); err != nil {
+			t.Fatal(err)
+		}
+		codes := s.Secrets["com.github.jessfraz.recovery"].Recovery
+		if len(codes) != 2 || codes[0].Code != "aaaa-1111" || codes[1].Code != "bbbb-2222" {
+			t.Errorf("migrating %s: unexpected recovery codes %+v", fixture, codes)
+		}
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "newer version",
+			data:    fmt.Sprintf(`{"version":%d,"secrets":{}}`, formatVersion+1),
+			wantErr: fmt.Sprintf("written by a newer version of pony (format v%d", formatVersion+1),
+		},
+		{
+			name:    "value not a string",
+			data:    `{"secrets":{"a.b":1}}`,
+			wantErr: "migrating secrets file from format v1 to v2 failed: secret for key a.b is not a string",
+		},
+		{
+			name:    "invalid json",
+			data:    `{"secrets":`,
+			wantErr: "unexpected end of JSON input",
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := migrate([]byte(tc.data))
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package main
+
+import (
+	"errors"
+)
+
+// Locking memory is not supported on this platform.
+func lockMemory() error {
+	return errors.New("not supported on this platform")
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const moveHelp = `Rename a secret or a namespace of secrets.`
+
+const moveLongHelp = moveHelp + `
+
+With --prefix every key under the namespace OLD is moved under NEW instead,
+com.old.token becomes com.new.token but com.older.token is left alone. All
+the secrets are renamed in a single write.
+
+Example:
+
+  pony mv com.gihtub.jessfraz.token com.github.jessfraz.token
+  pony mv --prefix com.old. com.new.`
+
+func (cmd *moveCommand) Name() string      { return "mv" }
+func (cmd *moveCommand) Args() string      { return "[OPTIONS] OLD NEW" }
+func (cmd *moveCommand) ShortHelp() string { return moveHelp }
+func (cmd *moveCommand) LongHelp() string  { return moveLongHelp }
+func (cmd *moveCommand) Hidden() bool      { return false }
+
+func (cmd *moveCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.force, "force", false, "overwrite secrets that already exist")
+	fs.BoolVar(&cmd.force, "f", false, "overwrite secrets that already exist")
+	fs.BoolVar(&cmd.prefix, "prefix", false, "rename every key under the namespace OLD")
+}
+
+type moveCommand struct {
+	force  bool
+	prefix bool
+}
+
+func (cmd *moveCommand) Run(ctx context.Context, args []string) error {
+	return renameSecrets(args, cmd.prefix, cmd.force, false)
+}
+
+const copyHelp = `Copy a secret or a namespace of secrets.`
+
+const copyLongHelp = copyHelp + `
+
+With --prefix every key under the namespace SRC is copied under DST instead.
+The copies start without history.`
+
+func (cmd *copyCommand) Name() string      { return "cp" }
+func (cmd *copyCommand) Args() string      { return "[OPTIONS] SRC DST" }
+func (cmd *copyCommand) ShortHelp() string { return copyHelp }
+func (cmd *copyCommand) LongHelp() string  { return copyLongHelp }
+func (cmd *copyCommand) Hidden() bool      { return false }
+
+func (cmd *copyCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.force, "force", false, "overwrite secrets that already exist")
+	fs.BoolVar(&cmd.force, "f", false, "overwrite secrets that already exist")
+	fs.BoolVar(&cmd.prefix, "prefix", false, "copy every key under the namespace SRC")
+}
+
+type copyCommand struct {
+	force  bool
+	prefix bool
+}
+
+func (cmd *copyCommand) Run(ctx context.Context, args []string) error {
+	return renameSecrets(args, cmd.prefix, cmd.force, true)
+}
+
+// renameSecrets moves or copies the secrets from the first to the second
+// key, or every key with the first prefix to the second prefix, and writes
+// the secrets file once.
+func renameSecrets(args []string, prefix, force, keep bool) error {
+	if len(args) < 2 {
+		return errors.New("must pass the source and destination keys")
+	}
+	src, dst := args[0], args[1]
+	if prefix {
+		// Only match whole key elements, so com.old does not match
+		// com.older.
+		src = strings.TrimSuffix(src, ".") + "."
+		dst = strings.TrimSuffix(dst, ".") + "."
+	}
+	if src == dst {
+		return errors.New("source and destination are the same")
+	}
+
+	// Map the old keys to the new ones.
+	renames := map[string]string{}
+	if prefix {
+		for key := range s.Secrets {
+			if strings.HasPrefix(key, src) {
+				renames[key] = dst + strings.TrimPrefix(key, src)
+			}
+		}
+		if len(renames) == 0 {
+			return fmt.Errorf("no secrets found with the prefix %s", src)
+		}
+	} else {
+		if _, ok := s.Secrets[src]; !ok {
+			return fmt.Errorf("secret for key %s does not exist", src)
+		}
+		renames[src] = dst
+	}
+
+	keys := []string{}
+	for key := range renames {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Check for conflicts before changing anything. A key that is moved away
+	// does not conflict.
+	conflicts := []string{}
+	for _, key := range keys {
+		to := renames[key]
+		if _, ok := s.Secrets[to]; ok && (keep || len(renames[to]) == 0) {
+			conflicts = append(conflicts, to)
+		}
+	}
+	if len(conflicts) == 1 && !force {
+		return fmt.Errorf("secret for key %s already exists, use `--force` to overwrite", conflicts[0])
+	}
+	if len(conflicts) > 1 && !force {
+		return fmt.Errorf("secrets for keys %s already exist, use `--force` to overwrite", strings.Join(conflicts, ", "))
+	}
+
+	secrets := map[string]secret{}
+	for _, key := range keys {
+		sec := s.Secrets[key]
+		if keep {
+			sec = copySecret(sec)
+		} else {
+			delete(s.Secrets, key)
+		}
+		secrets[renames[key]] = sec
+	}
+	for key, sec := range secrets {
+		s.Secrets[key] = sec
+	}
+
+	if err := writeSecretsFile(file, s); err != nil {
+		return err
+	}
+
+	verb := "Moved"
+	if keep {
+		verb = "Copied"
+	}
+	for _, key := range keys {
+		fmt.Printf("%s %s to %s\n", verb, key, renames[key])
+	}
+	return nil
+}
+
+// copySecret returns a new secret with the value and metadata of sec but
+// without its history.
+func copySecret(sec secret) secret {
+	now := time.Now().UTC()
+	c := secret{
+		Value:     sec.Value,
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Note:      sec.Note,
+		Tags:      append([]string(nil), sec.Tags...),
+	}
+	for _, code := range sec.Recovery {
+		c.Recovery = append(c.Recovery, recoveryCode{Code: code.Code, UsedAt: code.UsedAt})
+	}
+	return c
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/jessfraz/pony/otp"
+)
+
+const otpHelp = `Generate a one-time code from a stored 2FA seed.`
+
+const otpLongHelp = otpHelp + `
+
+The secret can be an otpauth:// URI or a base32 seed. Seeds default to a
+6 digit SHA1 TOTP code with a 30 second period, use the flags to change that.
+For HOTP keys the counter is incremented and saved after every code.
+
+Example:
+
+  pony create com.github.jessfraz.totp 'otpauth://totp/GitHub:jessfraz?secret=JBSWY3DPEHPK3PXP&issuer=GitHub'
+  pony otp com.github.jessfraz.totp`
+
+func (cmd *otpCommand) Name() string      { return "otp" }
+func (cmd *otpCommand) Args() string      { return "[OPTIONS] KEY" }
+func (cmd *otpCommand) ShortHelp() string { return otpHelp }
+func (cmd *otpCommand) LongHelp() string  { return otpLongHelp }
+func (cmd *otpCommand) Hidden() bool      { return false }
+
+func (cmd *otpCommand) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&cmd.hotp, "hotp", false, "use the seed as a counter based HOTP key")
+	fs.Int64Var(&cmd.counter, "counter", -1, "HOTP counter to use instead of the stored one")
+	fs.IntVar(&cmd.digits, "digits", 0, "number of digits of the code, 6 or 8, defaults to the one of the key")
+	fs.IntVar(&cmd.period, "period", 0, "number of seconds a TOTP code is valid for, defaults to the one of the key")
+	fs.StringVar(&cmd.algorithm, "algorithm", "", "hash algorithm, SHA1, SHA256, or SHA512, defaults to the one of the key")
+	fs.BoolVar(&cmd.copy, "copy", false, "copy the code to clipboard")
+}
+
+type otpCommand struct {
+	hotp      bool
+	counter   int64
+	digits    int
+	period    int
+	algorithm string
+	copy      bool
+}
+
+func (cmd *otpCommand) Run(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return errors.New("must pass a key")
+	}
+
+	key := args[0]
+	sec, ok := s.Secrets[key]
+	if !ok {
+		return fmt.Errorf("secret for key %s does not exist", key)
+	}
+
+	k, err := otp.Parse(sec.Value)
+	if err != nil {
+		return fmt.Errorf("secret for key %s is not an otp key: %v", key, err)
+	}
+
+	// Override the parameters of the key with the ones from the flags.
+	if cmd.hotp {
+		k.Type = otp.HOTP
+	}
+	if cmd.counter >= 0 {
+		k.Counter = uint64(cmd.counter)
+	}
+	if cmd.digits > 0 {
+		k.Digits = cmd.digits
+	}
+	if cmd.period > 0 {
+		k.Period = cmd.period
+	}
+	if len(cmd.algorithm) > 0 {
+		k.Algorithm = strings.ToUpper(cmd.algorithm)
+	}
+	if err := k.Validate(); err != nil {
+		return err
+	}
+
+	var code string
+	if k.Type == otp.HOTP {
+		counter := k.Counter
+		code = k.Code(counter)
+
+		// Save the next counter before showing the code so it is never
+		// handed out twice. The counter is not a new value of the secret,
+		// so it is not kept in the history.
+		k.Counter++
+		sec.Value = k.URI(key)
+		sec.UpdatedAt = time.Now().UTC()
+		s.Secrets[key] = sec
+		if err := writeSecretsFile(file, s); err != nil {
+			return fmt.Errorf("saving the hotp counter failed: %v", err)
+		}
+
+		fmt.Printf("%s (counter %d)\n", code, counter)
+	} else {
+		var remaining time.Duration
+		code, remaining = k.TOTP(time.Now())
+		fmt.Printf("%s (%ds remaining)\n", code, int(remaining.Seconds()))
+	}
+
+	if !cmd.copy {
+		// Return early.
+		return nil
+	}
+
+	// Copy to clipboard.
+	if err := clipboard.WriteAll(code); err != nil {
+		return fmt.Errorf("clipboard copy failed: %v", err)
+	}
+	fmt.Println("Copied to clipboard!")
+
+	return nil
+}
@@ -0,0 +1,199 @@
+// Package otp generates HOTP (RFC 4226) and TOTP (RFC 6238) one-time codes
+// from otpauth:// URIs or base32 seeds.
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TOTP is a time based key.
+	TOTP = "totp"
+	// HOTP is a counter based key.
+	HOTP = "hotp"
+
+	// DefaultPeriod is the number of seconds a TOTP code is valid for.
+	DefaultPeriod = 30
+	// DefaultDigits is the length of the codes.
+	DefaultDigits = 6
+	// DefaultAlgorithm is the HMAC hash function.
+	DefaultAlgorithm = "SHA1"
+)
+
+var algorithms = map[string]func() hash.Hash{
+	"SHA1":   sha1.New,
+	"SHA256": sha256.New,
+	"SHA512": sha512.New,
+}
+
+// Key holds the seed and parameters to generate codes with.
+type Key struct {
+	Type      string
+	Secret    []byte
+	Algorithm string
+	Digits    int
+	Period    int
+	Counter   uint64
+
+	// uri is the parsed otpauth:// URI, it is kept so the other parameters
+	// like the issuer survive writing the key back.
+	uri *url.URL
+}
+
+// Parse reads an otpauth:// URI or a base32 seed. A seed is a TOTP key with
+// the default parameters.
+func Parse(value string) (*Key, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(strings.ToLower(value), "otpauth://") {
+		return parseURI(value)
+	}
+
+	secret, err := decodeSecret(value)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		Type:      TOTP,
+		Secret:    secret,
+		Algorithm: DefaultAlgorithm,
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+	}, nil
+}
+
+func parseURI(value string) (*Key, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("parsing otpauth uri failed: %v", err)
+	}
+
+	k := &Key{
+		Type:      strings.ToLower(u.Host),
+		Algorithm: DefaultAlgorithm,
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+		uri:       u,
+	}
+	if k.Type != TOTP && k.Type != HOTP {
+		return nil, fmt.Errorf("unknown otp type %q, must be %s or %s", u.Host, TOTP, HOTP)
+	}
+
+	q := u.Query()
+	if k.Secret, err = decodeSecret(q.Get("secret")); err != nil {
+		return nil, err
+	}
+	if v := q.Get("algorithm"); len(v) > 0 {
+		k.Algorithm = strings.ToUpper(v)
+	}
+	if v := q.Get("digits"); len(v) > 0 {
+		if k.Digits, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid digits %q", v)
+		}
+	}
+	if v := q.Get("period"); len(v) > 0 {
+		if k.Period, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid period %q", v)
+		}
+	}
+	if v := q.Get("counter"); len(v) > 0 {
+		if k.Counter, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid counter %q", v)
+		}
+	} else if k.Type == HOTP {
+		return nil, errors.New("hotp uri is missing the counter")
+	}
+
+	return k, k.Validate()
+}
+
+// decodeSecret decodes a base32 seed, ignoring case, spaces, and padding.
+func decodeSecret(value string) ([]byte, error) {
+	value = strings.ToUpper(strings.Join(strings.Fields(value), ""))
+	value = strings.TrimRight(value, "=")
+	if len(value) == 0 {
+		return nil, errors.New("otp secret is empty")
+	}
+	b, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("decoding base32 otp secret failed: %v", err)
+	}
+	return b, nil
+}
+
+// Validate checks the parameters of the key are supported.
+func (k *Key) Validate() error {
+	if _, ok := algorithms[k.Algorithm]; !ok {
+		return fmt.Errorf("unsupported algorithm %q, must be SHA1, SHA256, or SHA512", k.Algorithm)
+	}
+	if k.Digits != 6 && k.Digits != 8 {
+		return fmt.Errorf("unsupported number of digits %d, must be 6 or 8", k.Digits)
+	}
+	if k.Period < 1 {
+		return fmt.Errorf("invalid period %d, must be at least 1 second", k.Period)
+	}
+	return nil
+}
+
+// Code returns the HOTP code for the counter.
+func (k *Key) Code(counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(algorithms[k.Algorithm], k.Secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, see RFC 4226 section 5.3.
+	offset := sum[len(sum)-1] & 0xf
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < k.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", k.Digits, code%mod)
+}
+
+// TOTP returns the code for the time and how long it is still valid.
+func (k *Key) TOTP(t time.Time) (string, time.Duration) {
+	period := int64(k.Period)
+	unix := t.Unix()
+	remaining := time.Duration(period-unix%period) * time.Second
+	return k.Code(uint64(unix / period)), remaining
+}
+
+// URI returns the key as an otpauth:// URI with the current counter. The
+// label is used for keys that were not parsed from a URI.
+func (k *Key) URI(label string) string {
+	u := k.uri
+	if u == nil {
+		u = &url.URL{Scheme: "otpauth", Path: "/" + label}
+	}
+	u.Host = k.Type
+
+	q := u.Query()
+	q.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k.Secret))
+	q.Set("algorithm", k.Algorithm)
+	q.Set("digits", strconv.Itoa(k.Digits))
+	if k.Type == HOTP {
+		q.Set("counter", strconv.FormatUint(k.Counter, 10))
+		q.Del("period")
+	} else {
+		q.Set("period", strconv.Itoa(k.Period))
+		q.Del("counter")
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,153 @@
+package otp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// The seeds of the test vectors in RFC 4226 and RFC 6238.
+const (
+	seedSHA1   = "12345678901234567890"
+	seedSHA256 = "12345678901234567890123456789012"
+	seedSHA512 = "1234567890123456789012345678901234567890123456789012345678901234"
+)
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226 appendix D.
+	expected := []string{
+		"755224",
+		"287082",
+		"359152",
+		"969429",
+		"338314",
+		"254676",
+		"287922",
+		"162583",
+		"399871",
+		"520489",
+	}
+
+	secret := base32.StdEncoding.EncodeToString([]byte(seedSHA1))
+	k, err := Parse("otpauth://hotp/test?secret=" + secret + "&counter=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for counter, code := range expected {
+		if got := k.Code(uint64(counter)); got != code {
+			t.Errorf("counter %d: expected %s, got %s", counter, code, got)
+		}
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 appendix B.
+	testCases := []struct {
+		time   int64
+		sha1   string
+		sha256 string
+		sha512 string
+	}{
+		{time: 59, sha1: "94287082", sha256: "46119246", sha512: "90693936"},
+		{time: 1111111109, sha1: "07081804", sha256: "68084774", sha512: "25091201"},
+		{time: 1111111111, sha1: "14050471", sha256: "67062674", sha512: "99943326"},
+		{time: 1234567890, sha1: "89005924", sha256: "91819424", sha512: "93441116"},
+		{time: 2000000000, sha1: "69279037", sha256: "90698825", sha512: "38618901"},
+		{time: 20000000000, sha1: "65353130", sha256: "77737706", sha512: "47863826"},
+	}
+
+	keys := map[string]*Key{
+		"SHA1":   {Type: TOTP, Secret: []byte(seedSHA1), Algorithm: "SHA1", Digits: 8, Period: 30},
+		"SHA256": {Type: TOTP, Secret: []byte(seedSHA256), Algorithm: "SHA256", Digits: 8, Period: 30},
+		"SHA512": {Type: TOTP, Secret: []byte(seedSHA512), Algorithm: "SHA512", Digits: 8, Period: 30},
+	}
+	for _, k := range keys {
+		if err := k.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range testCases {
+		now := time.Unix(tc.time, 0).UTC()
+		for algorithm, expected := range map[string]string{
+			"SHA1":   tc.sha1,
+			"SHA256": tc.sha256,
+			"SHA512": tc.sha512,
+		} {
+			code, remaining := keys[algorithm].TOTP(now)
+			if code != expected {
+				t.Errorf("%s at %d: expected %s, got %s", algorithm, tc.time, expected, code)
+			}
+			if want := time.Duration(30-tc.time%30) * time.Second; remaining != want {
+				t.Errorf("%s at %d: expected %s remaining, got %s", algorithm, tc.time, want, remaining)
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected Key
+		wantErr  bool
+	}{
+		{
+			value:    "GEZDGNBVGY3TQOJQ GEZDGNBVGY3TQOJQ",
+			expected: Key{Type: TOTP, Secret: []byte(seedSHA1), Algorithm: "SHA1", Digits: 6, Period: 30},
+		},
+		{
+			value:    "otpauth://totp/GitHub:jessfraz?secret=gezdgnbvgy3tqojqgezdgnbvgy3tqojq&algorithm=sha256&digits=8&period=60&issuer=GitHub",
+			expected: Key{Type: TOTP, Secret: []byte(seedSHA1), Algorithm: "SHA256", Digits: 8, Period: 60},
+		},
+		{
+			value:    "otpauth://hotp/test?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=7",
+			expected: Key{Type: HOTP, Secret: []byte(seedSHA1), Algorithm: "SHA1", Digits: 6, Period: 30, Counter: 7},
+		},
+		{value: "otpauth://hotp/test?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", wantErr: true},
+		{value: "otpauth://motp/test?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", wantErr: true},
+		{value: "otpauth://totp/test?secret=GEZDGNBVGY3TQOJQ&digits=7", wantErr: true},
+		{value: "otpauth://totp/test?secret=GEZDGNBVGY3TQOJQ&algorithm=MD5", wantErr: true},
+		{value: "not base32!", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		k, err := Parse(tc.value)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", tc.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.value, err)
+			continue
+		}
+		k.uri = nil
+		if string(k.Secret) != string(tc.expected.Secret) || k.Type != tc.expected.Type ||
+			k.Algorithm != tc.expected.Algorithm || k.Digits != tc.expected.Digits ||
+			k.Period != tc.expected.Period || k.Counter != tc.expected.Counter {
+			t.Errorf("%q: expected %+v, got %+v", tc.value, tc.expected, *k)
+		}
+	}
+}
+
+func TestURI(t *testing.T) {
+	k, err := Parse("otpauth://hotp/GitHub:jessfraz?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=1&issuer=GitHub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Counter++
+
+	p, err := Parse(k.URI("ignored"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Counter != 2 || p.Type != HOTP || string(p.Secret) != seedSHA1 {
+		t.Fatalf("expected the counter to be saved, got %+v", *p)
+	}
+	if issuer := p.uri.Query().Get("issuer"); issuer != "GitHub" {
+		t.Fatalf("expected the issuer to be kept, got %q", issuer)
+	}
+}
@@ -0,0 +1,53 @@
+package password
+
+import (
+	_ "embed" // Needed for the wordlist.
+	"errors"
+	"strings"
+	"sync"
+)
+
+// effLargeWordlist is the EFF large wordlist for diceware passphrases, see
+// https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases.
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist string
+
+var (
+	words     []string
+	wordsOnce sync.Once
+)
+
+// Passphrase returns a diceware style passphrase of n random words from the
+// EFF large wordlist joined by the separator.
+func Passphrase(n int, separator string) (string, error) {
+	if n < 1 {
+		return "", errors.New("passphrase must have at least one word")
+	}
+
+	words := wordlist()
+
+	p := make([]string, n)
+	for i := range p {
+		j, err := randInt(len(words))
+		if err != nil {
+			return "", err
+		}
+		p[i] = words[j]
+	}
+
+	return strings.Join(p, separator), nil
+}
+
+// wordlist returns the words of the EFF large wordlist.
+func wordlist() []string {
+	wordsOnce.Do(func() {
+		for _, line := range strings.Split(strings.TrimSpace(effLargeWordlist), "\n") {
+			// Each line is the dice roll and the word separated by a tab.
+			if i := strings.IndexByte(line, '\t'); i >= 0 {
+				words = append(words, strings.TrimSpace(line[i+1:]))
+			}
+		}
+	})
+	return words
+}
@@ -0,0 +1,62 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWordlist(t *testing.T) {
+	w := wordlist()
+	// 6^5 words, one for each roll of five dice.
+	if len(w) != 7776 {
+		t.Fatalf("expected 7776 words, got %d", len(w))
+	}
+	seen := map[string]bool{}
+	for _, word := range w {
+		if len(word) == 0 || strings.ContainsAny(word, " \t\r") {
+			t.Fatalf("invalid word %q", word)
+		}
+		if seen[word] {
+			t.Fatalf("duplicate word %q", word)
+		}
+		seen[word] = true
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	testCases := []struct {
+		n         int
+		separator string
+	}{
+		// Some words contain a dash, so splitting on one would not work.
+		{n: 1, separator: "."},
+		{n: 6, separator: "."},
+		{n: 4, separator: " "},
+		{n: 8, separator: "_"},
+	}
+
+	words := map[string]bool{}
+	for _, word := range wordlist() {
+		words[word] = true
+	}
+
+	for _, tc := range testCases {
+		p, err := Passphrase(tc.n, tc.separator)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Split(p, tc.separator)
+		if len(got) != tc.n {
+			t.Fatalf("expected %d words separated by %q, got %q", tc.n, tc.separator, p)
+		}
+		for _, word := range got {
+			if !words[word] {
+				t.Fatalf("%q is not in the wordlist", word)
+			}
+		}
+	}
+
+	if _, err := Passphrase(0, "-"); err == nil {
+		t.Fatal("expected an error for zero words")
+	}
+}
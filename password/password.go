@@ -0,0 +1,111 @@
+// Package password generates random passwords and diceware passphrases
+// using crypto/rand.
+package password
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+const (
+	lowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	upperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars  = "0123456789"
+	symbolChars = "!@#$%^&*()-_=+[]{};:,.<>/?~"
+
+	// ambiguousChars are the characters that are easily confused with each
+	// other when read.
+	ambiguousChars = "0O1lI|`'\";:,."
+)
+
+// Options define which characters a generated password contains.
+type Options struct {
+	Length int
+
+	Lower   bool
+	Upper   bool
+	Digits  bool
+	Symbols bool
+
+	// ExcludeAmbiguous leaves out characters that are easily confused
+	// like 0 and O.
+	ExcludeAmbiguous bool
+}
+
+// Generate a random password. The password contains at least one character
+// of each of the enabled character classes.
+func Generate(opts Options) (string, error) {
+	classes := []string{}
+	for _, c := range []struct {
+		enabled bool
+		chars   string
+	}{
+		{opts.Lower, lowerChars},
+		{opts.Upper, upperChars},
+		{opts.Digits, digitChars},
+		{opts.Symbols, symbolChars},
+	} {
+		if !c.enabled {
+			continue
+		}
+		chars := c.chars
+		if opts.ExcludeAmbiguous {
+			chars = removeChars(chars, ambiguousChars)
+		}
+		classes = append(classes, chars)
+	}
+
+	if len(classes) == 0 {
+		return "", errors.New("at least one character class must be enabled")
+	}
+	if opts.Length < len(classes) {
+		return "", errors.New("length must be at least the number of enabled character classes")
+	}
+
+	// Pick one character from each class first, then fill the rest from
+	// all of them.
+	all := strings.Join(classes, "")
+	p := make([]byte, opts.Length)
+	for i := range p {
+		chars := all
+		if i < len(classes) {
+			chars = classes[i]
+		}
+		n, err := randInt(len(chars))
+		if err != nil {
+			return "", err
+		}
+		p[i] = chars[n]
+	}
+
+	// Shuffle so the guaranteed characters are not always first.
+	for i := len(p) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		p[i], p[j] = p[j], p[i]
+	}
+
+	return string(p), nil
+}
+
+// randInt returns a uniform random number in [0, max).
+func randInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()), nil
+}
+
+func removeChars(s, chars string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(chars, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
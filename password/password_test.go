@@ -0,0 +1,68 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateClasses(t *testing.T) {
+	testCases := []struct {
+		opts    Options
+		classes []string
+	}{
+		{opts: Options{Length: 4, Lower: true, Upper: true, Digits: true, Symbols: true}, classes: []string{lowerChars, upperChars, digitChars, symbolChars}},
+		{opts: Options{Length: 2, Upper: true, Digits: true}, classes: []string{upperChars, digitChars}},
+		{opts: Options{Length: 32, Lower: true}, classes: []string{lowerChars}},
+	}
+
+	for _, tc := range testCases {
+		// Every class has to show up every time, not only most of the time.
+		for i := 0; i < 100; i++ {
+			p, err := Generate(tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(p) != tc.opts.Length {
+				t.Fatalf("%+v: expected length %d, got %q", tc.opts, tc.opts.Length, p)
+			}
+			for _, class := range tc.classes {
+				if !strings.ContainsAny(p, class) {
+					t.Fatalf("%+v: expected a character of %q in %q", tc.opts, class, p)
+				}
+			}
+			allowed := strings.Join(tc.classes, "")
+			for _, r := range p {
+				if !strings.ContainsRune(allowed, r) {
+					t.Fatalf("%+v: unexpected character %q in %q", tc.opts, r, p)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateExcludeAmbiguous(t *testing.T) {
+	opts := Options{Length: 64, Lower: true, Upper: true, Digits: true, Symbols: true, ExcludeAmbiguous: true}
+	for i := 0; i < 100; i++ {
+		p, err := Generate(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.ContainsAny(p, ambiguousChars) {
+			t.Fatalf("expected no ambiguous characters in %q", p)
+		}
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	testCases := []Options{
+		{Length: 3, Lower: true, Upper: true, Digits: true, Symbols: true},
+		{Length: 1, Lower: true, Upper: true},
+		{Length: 16},
+	}
+
+	for _, opts := range testCases {
+		if p, err := Generate(opts); err == nil {
+			t.Errorf("%+v: expected an error, got %q", opts, p)
+		}
+	}
+}
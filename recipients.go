@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+const recipientsHelp = `Manage the recipients the secrets file is encrypted to.`
+
+const recipientsLongHelp = recipientsHelp + `
+
+Commands:
+
+  ls              list the recipients
+  add KEYID...    add recipients and re-encrypt the secrets file
+  rm KEYID...     remove recipients and re-encrypt the secrets file`
+
+func (cmd *recipientsCommand) Name() string      { return "recipients" }
+func (cmd *recipientsCommand) Args() string      { return "[OPTIONS] ls|add|rm [KEYID...]" }
+func (cmd *recipientsCommand) ShortHelp() string { return recipientsHelp }
+func (cmd *recipientsCommand) LongHelp() string  { return recipientsLongHelp }
+func (cmd *recipientsCommand) Hidden() bool      { return false }
+
+func (cmd *recipientsCommand) Register(fs *flag.FlagSet) {}
+
+type recipientsCommand struct{}
+
+func (cmd *recipientsCommand) Run(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return errors.New("must pass a subcommand: ls, add, or rm")
+	}
+
+	switch args[0] {
+	case "ls":
+		if len(s.Recipients) == 0 {
+			fmt.Println("No recipients recorded, the secrets file is encrypted to your default key")
+			return nil
+		}
+		for _, r := range s.Recipients {
+			fmt.Println(r)
+		}
+		return nil
+	case "add":
+		if len(args) < 2 {
+			return errors.New("must pass a keyid to add")
+		}
+		if len(s.Recipients) == 0 {
+			// Keep whoever the file is encrypted to now, otherwise adding a
+			// recipient would lock the owner out of their own store.
+			current, err := currentRecipients(s)
+			if err != nil {
+				return fmt.Errorf("finding the key the secrets file is encrypted to failed, pass it with --keyid: %v", err)
+			}
+			s.Recipients = current
+		}
+		for _, r := range args[1:] {
+			if contains(s.Recipients, r) {
+				return fmt.Errorf("%s is already a recipient", r)
+			}
+			s.Recipients = append(s.Recipients, r)
+		}
+	case "rm":
+		if len(args) < 2 {
+			return errors.New("must pass a keyid to remove")
+		}
+		for _, r := range args[1:] {
+			if !contains(s.Recipients, r) {
+				return fmt.Errorf("%s is not a recipient", r)
+			}
+			s.Recipients = removeString(s.Recipients, r)
+		}
+	default:
+		return fmt.Errorf("unknown subcommand %q, must be one of ls, add, or rm", args[0])
+	}
+
+	// Re-encrypt the secrets file to the new set of recipients and make sure
+	// we can still read it.
+	if err := rewriteSecretsFile(s); err != nil {
+		return err
+	}
+
+	fmt.Printf("Re-encrypted secrets to %d recipient(s)\n", len(s.Recipients))
+	return nil
+}
+
+// currentRecipients returns who a secrets file without recorded recipients
+// is encrypted to: the --keyid values, otherwise the key of the identity.
+func currentRecipients(s secretFile) ([]string, error) {
+	if len(keyids.values) > 0 {
+		return keyids.values, nil
+	}
+	c, err := getCipher(writeBackend(s))
+	if err != nil {
+		return nil, err
+	}
+	recipients, err := c.DefaultRecipients()
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("no key found for the current identity")
+	}
+	return recipients, nil
+}
+
+// contains checks if the string is in the list.
+func contains(list []string, str string) bool {
+	for _, l := range list {
+		if l == str {
+			return true
+		}
+	}
+	return false
+}
+
+// removeString returns the list without the string.
+func removeString(list []string, str string) []string {
+	l := []string{}
+	for _, v := range list {
+		if v != str {
+			l = append(l, v)
+		}
+	}
+	return l
+}
+
+// equalLists checks if both lists hold the same strings in any order.
+func equalLists(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, v := range a {
+		if !contains(b, v) {
+			return false
+		}
+	}
+	return true
+}
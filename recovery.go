@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const recoveryHelp = `Use and track recovery codes.`
+
+const recoveryLongHelp = recoveryHelp + `
+
+Secrets with keys ending in .recovery hold a set of comma separated recovery
+codes. pony keeps track of which codes were used and when.
+
+Commands:
+
+  use KEY         print the next unused recovery code and mark it as used
+  status [KEY]    show how many recovery codes are left`
+
+func (cmd *recoveryCommand) Name() string      { return "recovery" }
+func (cmd *recoveryCommand) Args() string      { return "[OPTIONS] use|status [KEY]" }
+func (cmd *recoveryCommand) ShortHelp() string { return recoveryHelp }
+func (cmd *recoveryCommand) LongHelp() string  { return recoveryLongHelp }
+func (cmd *recoveryCommand) Hidden() bool      { return false }
+
+func (cmd *recoveryCommand) Register(fs *flag.FlagSet) {
+	fs.IntVar(&cmd.warnBelow, "warn-below", 3, "warn when fewer than this many recovery codes are left")
+}
+
+type recoveryCommand struct {
+	warnBelow int
+}
+
+// recoveryCode is a single recovery code, UsedAt is nil until it is used.
+type recoveryCode struct {
+	Code   string     `json:"code"`
+	UsedAt *time.Time `json:"used_at,omitempty"`
+}
+
+func (cmd *recoveryCommand) Run(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return errors.New("must pass a subcommand: use or status")
+	}
+
+	switch args[0] {
+	case "use":
+		if len(args) < 2 {
+			return errors.New("must pass a key")
+		}
+		return cmd.use(args[1])
+	case "status":
+		keys := args[1:]
+		if len(keys) == 0 {
+			for key, sec := range s.Secrets {
+				if len(sec.Recovery) > 0 {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+		}
+		return cmd.status(keys)
+	}
+
+	return fmt.Errorf("unknown subcommand %q, must be one of use or status", args[0])
+}
+
+// use prints the next unused recovery code and marks it as used.
+func (cmd *recoveryCommand) use(key string) error {
+	sec, ok := s.Secrets[key]
+	if !ok {
+		return fmt.Errorf("secret for key %s does not exist", key)
+	}
+	if len(sec.Recovery) == 0 {
+		return fmt.Errorf("secret for key %s has no recovery codes", key)
+	}
+
+	for i, code := range sec.Recovery {
+		if code.UsedAt != nil {
+			continue
+		}
+
+		now := time.Now().UTC()
+		sec.Recovery[i].UsedAt = &now
+		s.Secrets[key] = sec
+		if err := writeSecretsFile(file, s); err != nil {
+			return err
+		}
+
+		fmt.Println(code.Code)
+		cmd.warn(key, sec)
+		return nil
+	}
+
+	return fmt.Errorf("all recovery codes for key %s have been used", key)
+}
+
+// status prints how many recovery codes are left for the keys.
+func (cmd *recoveryCommand) status(keys []string) error {
+	w := tabwriter.NewWriter(os.Stdout, 20, 1, 3, ' ', 0)
+
+	// print header
+	fmt.Fprintln(w, "KEY\tREMAINING\tTOTAL\tLAST USED")
+
+	for _, key := range keys {
+		sec, ok := s.Secrets[key]
+		if !ok {
+			return fmt.Errorf("secret for key %s does not exist", key)
+		}
+		if len(sec.Recovery) == 0 {
+			return fmt.Errorf("secret for key %s has no recovery codes", key)
+		}
+
+		var lastUsed time.Time
+		for _, code := range sec.Recovery {
+			if code.UsedAt != nil && code.UsedAt.After(lastUsed) {
+				lastUsed = *code.UsedAt
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", key, unusedRecoveryCodes(sec), len(sec.Recovery), formatTime(lastUsed))
+	}
+
+	w.Flush()
+
+	for _, key := range keys {
+		cmd.warn(key, s.Secrets[key])
+	}
+	return nil
+}
+
+// warn warns the user when a set of recovery codes is nearly used up.
+func (cmd *recoveryCommand) warn(key string, sec secret) {
+	n := unusedRecoveryCodes(sec)
+	if n == 0 {
+		logrus.Warnf("all recovery codes for %s have been used, generate new ones", key)
+	} else if n < cmd.warnBelow {
+		logrus.Warnf("only %d recovery code(s) left for %s, generate new ones soon", n, key)
+	}
+}
+
+// unusedRecoveryCodes returns the number of recovery codes that were not
+// used yet.
+func unusedRecoveryCodes(sec secret) int {
+	n := 0
+	for _, code := range sec.Recovery {
+		if code.UsedAt == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// isRecoveryKey checks if the key holds recovery codes.
+func isRecoveryKey(key string) bool {
+	return strings.HasSuffix(key, ".recovery")
+}
+
+// newRecoveryCodes splits the comma separated value into recovery codes.
+// Codes that were already in the old set keep when they were used.
+func newRecoveryCodes(value string, old []recoveryCode) []recoveryCode {
+	used := map[string]*time.Time{}
+	for _, code := range old {
+		used[code.Code] = code.UsedAt
+	}
+
+	codes := []recoveryCode{}
+	for _, c := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\t'
+	}) {
+		codes = append(codes, recoveryCode{Code: c, UsedAt: used[c]})
+	}
+	return codes
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/jessfraz/pony/age"
+)
+
+const rekeyHelp = `Re-encrypt the secrets file to a new key.`
+
+func (cmd *rekeyCommand) Name() string      { return "rekey" }
+func (cmd *rekeyCommand) Args() string      { return "[OPTIONS]" }
+func (cmd *rekeyCommand) ShortHelp() string { return rekeyHelp }
+func (cmd *rekeyCommand) LongHelp() string  { return rekeyHelp }
+func (cmd *rekeyCommand) Hidden() bool      { return false }
+
+func (cmd *rekeyCommand) Register(fs *flag.FlagSet) {
+	fs.Var(&cmd.to, "to", "keyids or age recipients to re-encrypt to, comma separated or repeated")
+	fs.StringVar(&cmd.verifyIdentity, "verify-identity", "", "age identity file of the new key to verify the re-encrypted file with, defaults to --age-identity")
+}
+
+type rekeyCommand struct {
+	to             listFlag
+	verifyIdentity string
+}
+
+func (cmd *rekeyCommand) Run(ctx context.Context, args []string) error {
+	if len(cmd.to.values) < 1 {
+		return errors.New("must pass the keyid to re-encrypt to with --to")
+	}
+
+	// Re-encrypt to the new recipients. We set the backend to the one we
+	// are writing with so the recipients are not replaced with --keyid.
+	n := s
+	n.Recipients = cmd.to.values
+	if len(backend) > 0 {
+		n.backend = backend
+	}
+
+	// With age the new recipients usually have their own identity file, we
+	// need it to check the new file decrypts.
+	identity := age.IdentityFile
+	if len(cmd.verifyIdentity) > 0 {
+		home, err := getHome()
+		if err != nil {
+			return err
+		}
+		identity = strings.Replace(cmd.verifyIdentity, homeShortcut, home, 1)
+	} else if writeBackend(n) == "age" {
+		self, err := age.Recipients()
+		if err != nil {
+			return err
+		}
+		if !containsAny(self, cmd.to.values) {
+			return fmt.Errorf("%s can not decrypt for %s, pass the identity file of the new key with --verify-identity", identity, strings.Join(cmd.to.values, ", "))
+		}
+	}
+
+	if err := rewriteSecretsFileWith(n, identity); err != nil {
+		return err
+	}
+
+	fmt.Printf("Re-encrypted secrets to %s\n", strings.Join(cmd.to.values, ", "))
+	return nil
+}
+
+// rewriteSecretsFile re-encrypts the secrets file with n and verifies the new
+// file still decrypts to the same secrets, otherwise the old file is put back.
+func rewriteSecretsFile(n secretFile) error {
+	return rewriteSecretsFileWith(n, age.IdentityFile)
+}
+
+// rewriteSecretsFileWith is rewriteSecretsFile verifying with the given age
+// identity file.
+func rewriteSecretsFileWith(n secretFile, identity string) error {
+	// Keep a backup of the old file until we know the new one is good.
+	backup := file + ".rekey"
+	if err := copyFile(file, backup); err != nil {
+		return fmt.Errorf("backing up %s failed: %v", file, err)
+	}
+
+	if err := writeSecretsFile(file, n); err != nil {
+		return restoreBackup(backup, err)
+	}
+
+	// Verify the new file decrypts to the same secrets.
+	current := age.IdentityFile
+	age.IdentityFile = identity
+	v, err := readSecretsFile(file)
+	age.IdentityFile = current
+	if err != nil {
+		return restoreBackup(backup, fmt.Errorf("verifying the re-encrypted file failed: %v", err))
+	}
+	if (len(v.Secrets) > 0 || len(n.Secrets) > 0) && !reflect.DeepEqual(v.Secrets, n.Secrets) {
+		return restoreBackup(backup, errors.New("verifying the re-encrypted file failed: secrets do not match"))
+	}
+
+	if err := os.Remove(backup); err != nil {
+		return fmt.Errorf("removing backup %s failed: %v", backup, err)
+	}
+	return nil
+}
+
+// containsAny checks if any of the strings is in the list.
+func containsAny(list []string, strs []string) bool {
+	for _, str := range strs {
+		if contains(list, str) {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreBackup moves the backup back in place of the secrets file and
+// returns the original error.
+func restoreBackup(backup string, err error) error {
+	if rerr := os.Rename(backup, file); rerr != nil {
+		return fmt.Errorf("%v; restoring backup %s failed: %v", err, backup, rerr)
+	}
+	return fmt.Errorf("%v; restored the previous secrets file", err)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	ponyage "github.com/jessfraz/pony/age"
+)
+
+// newAgeIdentity writes a new age identity to a file in dir and returns the
+// path of the file and the recipient for it.
+func newAgeIdentity(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	i, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(p, []byte(i.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return p, i.Recipient().String()
+}
+
+// setupAgeStore writes a secrets file encrypted with age to the identity and
+// reads it back into s.
+func setupAgeStore(t *testing.T, identity string) {
+	t.Helper()
+	oldFile, oldBackend, oldIdentity, oldSecrets := file, backend, ponyage.IdentityFile, s
+	t.Cleanup(func() {
+		file, backend, ponyage.IdentityFile, s = oldFile, oldBackend, oldIdentity, oldSecrets
+	})
+
+	file = filepath.Join(t.TempDir(), "pony")
+	backend = "age"
+	ponyage.IdentityFile = identity
+
+	now := time.Now().UTC()
+	n := secretFile{Secrets: map[string]secret{
+		"com.github.jessfraz.token": {Value: "s3cret", Version: 1, CreatedAt: now, UpdatedAt: now},
+	}}
+	if err := writeSecretsFile(file, n); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	s, err = readSecretsFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRekeyToSecondAgeKey(t *testing.T) {
+	dir := t.TempDir()
+	id1, _ := newAgeIdentity(t, dir, "key1.txt")
+	id2, r2 := newAgeIdentity(t, dir, "key2.txt")
+	setupAgeStore(t, id1)
+
+	cmd := &rekeyCommand{to: listFlag{values: []string{r2}}, verifyIdentity: id2}
+	if err := cmd.Run(context.Background(), nil); err != nil {
+		t.Fatalf("rekey failed: %v", err)
+	}
+
+	ponyage.IdentityFile = id2
+	v, err := readSecretsFile(file)
+	if err != nil {
+		t.Fatalf("decrypting with the new key failed: %v", err)
+	}
+	if got := v.Secrets["com.github.jessfraz.token"].Value; got != "s3cret" {
+		t.Fatalf("expected s3cret, got %q", got)
+	}
+
+	ponyage.IdentityFile = id1
+	if _, err := readSecretsFile(file); err == nil {
+		t.Fatal("expected the old key to no longer decrypt the file")
+	}
+}
+
+func TestRekeyWithoutNewIdentity(t *testing.T) {
+	dir := t.TempDir()
+	id1, _ := newAgeIdentity(t, dir, "key1.txt")
+	_, r2 := newAgeIdentity(t, dir, "key2.txt")
+	setupAgeStore(t, id1)
+
+	cmd := &rekeyCommand{to: listFlag{values: []string{r2}}}
+	if err := cmd.Run(context.Background(), nil); err == nil {
+		t.Fatal("expected rekey to fail without the identity of the new key")
+	}
+
+	// The file is left alone.
+	v, err := readSecretsFile(file)
+	if err != nil {
+		t.Fatalf("decrypting with the old key failed: %v", err)
+	}
+	if got := v.Secrets["com.github.jessfraz.token"].Value; got != "s3cret" {
+		t.Fatalf("expected s3cret, got %q", got)
+	}
+}
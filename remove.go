@@ -25,6 +25,14 @@ func (cmd *removeCommand) Run(ctx context.Context, args []string) error {
 	}
 
 	key := args[0]
+	if agent != nil {
+		if err := agent.remove(key); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted secret key %s\n", key)
+		return nil
+	}
+
 	if _, ok := s.Secrets[key]; !ok {
 		return fmt.Errorf("secret for key %s does not exist", key)
 	}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"text/template"
+)
+
+const renderHelp = `Render a Go template with secrets to a file.`
+
+const renderLongHelp = renderHelp + `
+
+The template can use these functions:
+
+  {{ secret "com.github.bot.token" }}    the value of a secret
+  {{ secrets "com.aws.*" }}              a map of keys to values for the secrets
+                                         matching a glob pattern
+
+Example ~/.netrc template:
+
+  machine github.com login bot password {{ secret "com.github.bot.token" }}`
+
+func (cmd *renderCommand) Name() string      { return "render" }
+func (cmd *renderCommand) Args() string      { return "[OPTIONS] TEMPLATE" }
+func (cmd *renderCommand) ShortHelp() string { return renderHelp }
+func (cmd *renderCommand) LongHelp() string  { return renderLongHelp }
+func (cmd *renderCommand) Hidden() bool      { return false }
+
+func (cmd *renderCommand) Register(fs *flag.FlagSet) {
+	fs.StringVar(&cmd.output, "o", "", "file to write the output to, defaults to stdout")
+	fs.StringVar(&cmd.output, "output", "", "file to write the output to, defaults to stdout")
+	fs.BoolVar(&cmd.allowMissing, "allow-missing", false, "render missing secrets as empty instead of failing")
+}
+
+type renderCommand struct {
+	output       string
+	allowMissing bool
+}
+
+func (cmd *renderCommand) Run(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return errors.New("must pass a template")
+	}
+
+	b, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("reading template failed: %v", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(args[0])).Funcs(template.FuncMap{
+		"secret":  cmd.secret,
+		"secrets": cmd.secrets,
+	}).Parse(string(b))
+	if err != nil {
+		return fmt.Errorf("parsing template failed: %v", err)
+	}
+	if cmd.allowMissing {
+		tmpl = tmpl.Option("missingkey=zero")
+	} else {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+
+	// The template data is the map of keys to values.
+	data := map[string]string{}
+	for key, sec := range s.Secrets {
+		data[key] = sec.Value
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("rendering template failed: %v", err)
+	}
+
+	return writeOutput(cmd.output, buf.Bytes())
+}
+
+// secret is the template function that returns the value of a secret.
+func (cmd *renderCommand) secret(key string) (string, error) {
+	sec, ok := s.Secrets[key]
+	if !ok && !cmd.allowMissing {
+		return "", fmt.Errorf("secret for key %s does not exist", key)
+	}
+	return sec.Value, nil
+}
+
+// secrets is the template function that returns the secrets with keys
+// matching the glob pattern.
+func (cmd *renderCommand) secrets(pattern string) (map[string]string, error) {
+	m := map[string]string{}
+	for key, sec := range s.Secrets {
+		ok, err := path.Match(pattern, key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		if ok {
+			m[key] = sec.Value
+		}
+	}
+	if len(m) == 0 && !cmd.allowMissing {
+		return nil, fmt.Errorf("no secrets match the pattern %s", pattern)
+	}
+	return m, nil
+}
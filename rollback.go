@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+const rollbackHelp = `Restore a previous value of a secret, defaults to the last one.`
+
+func (cmd *rollbackCommand) Name() string      { return "rollback" }
+func (cmd *rollbackCommand) Args() string      { return "[OPTIONS] KEY [VERSION]" }
+func (cmd *rollbackCommand) ShortHelp() string { return rollbackHelp }
+func (cmd *rollbackCommand) LongHelp() string  { return rollbackHelp }
+func (cmd *rollbackCommand) Hidden() bool      { return false }
+
+func (cmd *rollbackCommand) Register(fs *flag.FlagSet) {}
+
+type rollbackCommand struct{}
+
+func (cmd *rollbackCommand) Run(ctx context.Context, args []string) error {
+	if len(args) < 1 {
+		return errors.New("must pass a key")
+	}
+
+	key := args[0]
+	sec, ok := s.Secrets[key]
+	if !ok {
+		return fmt.Errorf("secret for key %s does not exist", key)
+	}
+	if len(sec.History) == 0 {
+		return fmt.Errorf("secret for key %s has no previous values", key)
+	}
+
+	// Get the version to restore.
+	v := sec.History[len(sec.History)-1]
+	if len(args) > 1 {
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("parsing version %q failed: %v", args[1], err)
+		}
+		if v, ok = sec.getVersion(version); !ok {
+			return fmt.Errorf("version %d of secret for key %s does not exist, see `pony history %s`", version, key, key)
+		}
+	}
+
+	// Restoring saves the current value in the history as well, so a
+	// rollback can be undone.
+	if err := s.setKeyValue(key, v.Value, true, "", nil); err != nil {
+		return err
+	}
+
+	fmt.Printf("Rolled back %s to version %d\n", key, v.Version)
+	return nil
+}
@@ -0,0 +1,25 @@
+//go:build darwin || dragonfly || freebsd || linux || nacl || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd linux nacl netbsd openbsd solaris
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// forwardSignals are the signals passed on to commands run by pony.
+var forwardSignals = []os.Signal{
+	syscall.SIGHUP,
+	syscall.SIGTERM,
+	syscall.SIGUSR1,
+	syscall.SIGUSR2,
+}
+
+// terminalSignals are the signals the terminal sends to the whole foreground
+// process group. Commands run by pony already get them, pony only has to
+// survive them.
+var terminalSignals = []os.Signal{
+	syscall.SIGINT,
+	syscall.SIGQUIT,
+}
@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os"
+)
+
+// forwardSignals are the signals passed on to commands run by pony.
+var forwardSignals []os.Signal
+
+// terminalSignals are the signals the console sends to every process attached
+// to it. Commands run by pony already get them, pony only has to survive them.
+var terminalSignals = []os.Signal{
+	os.Interrupt,
+}
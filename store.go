@@ -3,15 +3,93 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"io/ioutil"
 	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/jessfraz/pony/gpg"
+	"github.com/sirupsen/logrus"
 )
 
 // secretFile is the structure for how the decrypted secret filestorage is organized.
 type secretFile struct {
-	Secrets map[string]string `json:"secrets,omitempty"`
+	Version int               `json:"version,omitempty"`
+	Secrets map[string]secret `json:"secrets,omitempty"`
+
+	// Recipients are the keyids the file is encrypted to. They are
+	// persisted so that every write encrypts to the same people.
+	Recipients []string `json:"recipients,omitempty"`
+
+	// backend is the name of the encryption backend the file was read with.
+	backend string
+}
+
+// secret is a single secret value and its metadata.
+type secret struct {
+	Value     string    `json:"value"`
+	Version   int       `json:"version,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Note      string    `json:"note,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+
+	// History holds the previous values of the secret, oldest first.
+	History []secretVersion `json:"history,omitempty"`
+
+	// Recovery holds the recovery codes of keys ending in .recovery and
+	// when they were used.
+	Recovery []recoveryCode `json:"recovery,omitempty"`
+}
+
+// secretVersion is a previous value of a secret.
+type secretVersion struct {
+	Version   int       `json:"version"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// pushHistory saves the current value of the secret in its history, keeping
+// at most depth previous values.
+func (sec *secret) pushHistory(depth int) {
+	// Secrets migrated from older files do not have a version yet.
+	if sec.Version == 0 {
+		sec.Version = 1
+	}
+
+	if depth < 1 {
+		sec.History = nil
+		return
+	}
+
+	sec.History = append(sec.History, secretVersion{
+		Version:   sec.Version,
+		Value:     sec.Value,
+		UpdatedAt: sec.UpdatedAt,
+	})
+	if len(sec.History) > depth {
+		sec.History = sec.History[len(sec.History)-depth:]
+	}
+}
+
+// getVersion returns the previous value of the secret with the given
+// version.
+func (sec secret) getVersion(version int) (secretVersion, bool) {
+	for _, v := range sec.History {
+		if v.Version == version {
+			return v, true
+		}
+	}
+	return secretVersion{}, false
+}
+
+// hasTags checks if the secret has all the given tags.
+func (sec secret) hasTags(tags []string) bool {
+	for _, t := range tags {
+		if !contains(sec.Tags, t) {
+			return false
+		}
+	}
+	return true
 }
 
 // readSecretsFile opens the secrets filestore, decrypts the file contents,
@@ -22,16 +100,42 @@ func readSecretsFile(filename string) (s secretFile, err error) {
 		return s, err
 	}
 
+	// Open and read the file.
+	body, err := ioutil.ReadFile(f)
+	if err != nil {
+		return s, err
+	}
+
+	// Get the format version and the backend the file was encrypted with.
+	e, b, err := parseEnvelope(body)
+	if err != nil {
+		return s, fmt.Errorf("reading %s failed: %v", f, err)
+	}
+	if e.Version > formatVersion {
+		return s, fmt.Errorf("reading %s failed: %v", f, errNewerFormat(e.Version))
+	}
+	c, err := getCipher(e.Backend)
+	if err != nil {
+		return s, fmt.Errorf("reading %s failed: %v", f, err)
+	}
+
 	// Decrypt the file.
-	file, err := gpg.Decrypt(f)
+	b, err = c.Decrypt(b)
+	if err != nil {
+		return s, fmt.Errorf("%s decrypt file failed: %v", e.Backend, err)
+	}
+
+	// Upgrade files written by older versions of pony.
+	b, err = migrate(b)
 	if err != nil {
-		return s, fmt.Errorf("gpg decrypt file failed: %v", err)
+		return s, fmt.Errorf("reading %s failed: %v", f, err)
 	}
 
 	// Unmarshal the contents.
-	if err = json.NewDecoder(file).Decode(&s); err != nil {
+	if err = json.Unmarshal(b, &s); err != nil {
 		return s, err
 	}
+	s.backend = e.Backend
 
 	return s, err
 }
@@ -39,43 +143,104 @@ func readSecretsFile(filename string) (s secretFile, err error) {
 // writeSecretsFile takes a SecretsFile struct and marshals, encrypts, and
 // writes it to the secrets filestore.
 func writeSecretsFile(filename string, s secretFile) error {
-	b, err := json.Marshal(s)
+	name := writeBackend(s)
+	c, err := getCipher(name)
 	if err != nil {
-		return fmt.Errorf("marshaling secret file to json failed: %v", s)
+		return err
 	}
 
-	// Encrypt the string to the file
-	eb, err := gpg.Encrypt(b, keyid)
+	// Use the recipients recorded in the file unless there are none yet or
+	// we are switching backends, since recipients only work for one backend.
+	if len(s.Recipients) == 0 || (len(s.backend) > 0 && s.backend != name) {
+		s.Recipients = keyids.values
+	} else if keyids.changed && !equalLists(s.Recipients, keyids.values) {
+		logrus.Warnf("ignoring --keyid, the secrets file is encrypted to %s, use `pony recipients` to change them", strings.Join(s.Recipients, ", "))
+	}
+
+	// Always write the current format version, this migrates older files.
+	s.Version = formatVersion
+	b, err := json.Marshal(s)
 	if err != nil {
-		return fmt.Errorf("gpg encrypt on file create failed: %v", err)
+		return fmt.Errorf("marshaling secret file to json failed: %v", err)
 	}
 
-	f, err := os.Create(file)
+	// Encrypt the string to the file
+	eb, err := c.Encrypt(b, s.Recipients)
 	if err != nil {
-		return fmt.Errorf("could not create filestore for secrets at %s: %v", file, err)
+		return fmt.Errorf("%s encrypt on file create failed: %v", name, err)
 	}
-	defer f.Close()
 
-	if _, err := f.Write(eb); err != nil {
+	e := envelope{Version: formatVersion, Backend: name}
+	if err := writeFileAtomic(filename, e.encode(eb), backups); err != nil {
 		return fmt.Errorf("writing to file failed: %v", err)
 	}
 
 	return nil
 }
 
-func (s *secretFile) setKeyValue(key, value string, force bool) error {
-	// Add the key value pair to secrets.
-	if len(s.Secrets) == 0 {
-		s.Secrets = map[string]string{
-			key: value,
-		}
-	} else {
-		// Check if the key already exists and warn the user we are overwriting.
-		if _, ok := s.Secrets[key]; ok && !force {
-			return fmt.Errorf("secret for key %s already exists, use `--force` to overwrite", key)
-		}
-		s.Secrets[key] = value
+// writeBackend returns the name of the backend the secrets file is written
+// with: the one given by the user, otherwise the one the file was already
+// encrypted with.
+func writeBackend(s secretFile) string {
+	if len(backend) > 0 {
+		return backend
+	}
+	if len(s.backend) > 0 {
+		return s.backend
+	}
+	return defaultBackend
+}
+
+// setKeyValue sets the value for the key and writes the secrets file. The
+// note and tags are only changed if they are not empty, so they are kept
+// when overwriting a value.
+func (s *secretFile) setKeyValue(key, value string, force bool, note string, tags []string) error {
+	if agent != nil {
+		return agent.create(key, value, force, note, tags)
+	}
+
+	if err := s.setSecret(key, value, force, note, tags); err != nil {
+		return err
 	}
 
 	return writeSecretsFile(file, *s)
 }
+
+// setSecret sets the value for the key without writing the secrets file.
+func (s *secretFile) setSecret(key, value string, force bool, note string, tags []string) error {
+	if s.Secrets == nil {
+		s.Secrets = map[string]secret{}
+	}
+
+	// Check if the key already exists and warn the user we are overwriting.
+	now := time.Now().UTC()
+	sec, ok := s.Secrets[key]
+	if ok && !force {
+		return fmt.Errorf("secret for key %s already exists, use `--force` to overwrite", key)
+	}
+	if !ok {
+		sec.CreatedAt = now
+		sec.Version = 1
+	}
+	if ok && sec.Value != value {
+		// Keep the old value around so it can be rolled back.
+		sec.pushHistory(historyDepth)
+		sec.Version++
+	}
+	if isRecoveryKey(key) && (!ok || sec.Value != value) {
+		sec.Recovery = newRecoveryCodes(value, sec.Recovery)
+	}
+	sec.Value = value
+	sec.UpdatedAt = now
+	if len(note) > 0 {
+		sec.Note = note
+	}
+	if len(tags) > 0 {
+		sec.Tags = tags
+	}
+
+	// Add the secret to secrets.
+	s.Secrets[key] = sec
+
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// swapDirs exchanges the directories a and b with a single rename.
+func swapDirs(a, b string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+	if err == unix.ENOSYS || err == unix.EINVAL {
+		// The kernel or the filesystem can not exchange them.
+		return renameDirs(a, b)
+	}
+	return err
+}
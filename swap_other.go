@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+// swapDirs exchanges the directories a and b. Only Linux can do this with a
+// single rename, elsewhere b is moved out of the way first.
+func swapDirs(a, b string) error {
+	return renameDirs(a, b)
+}